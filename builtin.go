@@ -29,11 +29,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"os"
 	"strconv"
 	"strings"
 
+	"src.agwa.name/go-listener/internal/params"
 	"src.agwa.name/go-listener/proxy"
 	"src.agwa.name/go-listener/unix"
 )
@@ -43,6 +45,7 @@ func init() {
 	RegisterListenerType("fdname", openFDNameListener)
 	RegisterListenerType("tcp", openTCPListener)
 	RegisterListenerType("unix", openUnixListener)
+	RegisterListenerType("unixpacket", openUnixPacketListener)
 	RegisterListenerType("proxy", openProxyListener)
 }
 
@@ -66,7 +69,7 @@ func openFDListener(params map[string]interface{}, arg string) (net.Listener, er
 	file := os.NewFile(uintptr(fd), fdString)
 	defer file.Close()
 
-	return net.FileListener(file)
+	return openFileListener(params, file)
 }
 
 func openFDNameListener(params map[string]interface{}, arg string) (net.Listener, error) {
@@ -91,13 +94,50 @@ func openFDNameListener(params map[string]interface{}, arg string) (net.Listener
 		if ithname == name {
 			file := os.NewFile(uintptr(3+i), name)
 			defer file.Close()
-			return net.FileListener(file)
+			return openFileListener(params, file)
 		}
 	}
 
 	return nil, fmt.Errorf("fdname: %q not found in $LISTEN_FDNAMES", name)
 }
 
+func openFileListener(params map[string]interface{}, file *os.File) (net.Listener, error) {
+	inner, err := net.FileListener(file)
+	if err != nil {
+		return nil, err
+	}
+	unixListener, ok := inner.(*net.UnixListener)
+	if !ok {
+		return inner, nil
+	}
+	config, err := getUnixListenConfig(params)
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+	listener, err := config.NewListener(unixListener)
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+func getUnixListenConfig(options map[string]interface{}) (*unix.ListenConfig, error) {
+	config := &unix.ListenConfig{Mode: 0666}
+	var err error
+	if config.AllowUIDs, err = params.Ints(options, "allow_uid"); err != nil {
+		return nil, fmt.Errorf("UNIX listener has invalid allow_uid: %w", err)
+	}
+	if config.AllowGIDs, err = params.Ints(options, "allow_gid"); err != nil {
+		return nil, fmt.Errorf("UNIX listener has invalid allow_gid: %w", err)
+	}
+	if config.Lock, err = params.Bool(options, "lock"); err != nil {
+		return nil, fmt.Errorf("UNIX listener has invalid lock: %w", err)
+	}
+	return config, nil
+}
+
 func openTCPListener(params map[string]interface{}, arg string) (net.Listener, error) {
 	var ipString string
 	var portString string
@@ -147,6 +187,14 @@ func openTCPListener(params map[string]interface{}, arg string) (net.Listener, e
 }
 
 func openUnixListener(params map[string]interface{}, arg string) (net.Listener, error) {
+	return openUnixNetworkListener("unix", params, arg)
+}
+
+func openUnixPacketListener(params map[string]interface{}, arg string) (net.Listener, error) {
+	return openUnixNetworkListener("unixpacket", params, arg)
+}
+
+func openUnixNetworkListener(network string, params map[string]interface{}, arg string) (net.Listener, error) {
 	var path string
 	if arg != "" {
 		path = arg
@@ -155,15 +203,112 @@ func openUnixListener(params map[string]interface{}, arg string) (net.Listener,
 	} else {
 		return nil, errors.New("path not specified for UNIX listener")
 	}
-	return unix.Listen(path, 0666)
+	config, err := getUnixListenConfig(params)
+	if err != nil {
+		return nil, err
+	}
+	config.Network = network
+	if strings.HasPrefix(path, "@") {
+		return config.ListenAbstract(path[1:])
+	}
+	return config.Listen(path)
 }
 
-func openProxyListener(params map[string]interface{}, arg string) (net.Listener, error) {
+func parseProxyVersions(str string) (proxy.Versions, bool) {
+	switch str {
+	case "v1":
+		return proxy.Version1, true
+	case "v2":
+		return proxy.Version2, true
+	case "any":
+		return proxy.AnyVersion, true
+	default:
+		return 0, false
+	}
+}
+
+func openProxyListener(options map[string]interface{}, arg string) (net.Listener, error) {
+	config := new(proxy.Config)
+	if str, ok := options["version"].(string); ok {
+		if config.Versions, ok = parseProxyVersions(str); !ok {
+			return nil, fmt.Errorf("proxy listener has invalid version %q; must be v1, v2, or any", str)
+		}
+	}
+
+	trusted, err := params.Strings(options, "trusted")
+	if err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid trusted: %w", err)
+	}
+	for _, str := range trusted {
+		network, err := proxy.ParseNetwork(str)
+		if err != nil {
+			return nil, fmt.Errorf("proxy listener has invalid trusted: %w", err)
+		}
+		config.TrustedNetworks = append(config.TrustedNetworks, network)
+	}
+	if config.TrustedUIDs, err = params.Ints(options, "trusted_uid"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid trusted_uid: %w", err)
+	}
+	switch untrusted, _ := options["untrusted"].(string); untrusted {
+	case "", "reject":
+		config.Untrusted = proxy.RejectUntrusted
+	case "accept":
+		config.Untrusted = proxy.AcceptUntrusted
+	default:
+		return nil, fmt.Errorf("proxy listener has invalid untrusted %q; must be reject or accept", untrusted)
+	}
+	if config.Optional, err = params.Bool(options, "optional"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid optional: %w", err)
+	}
+	switch local, _ := options["local"].(string); local {
+	case "", "pass":
+		config.Local = proxy.PassLocal
+	case "answer":
+		config.Local = proxy.AnswerLocal
+	default:
+		return nil, fmt.Errorf("proxy listener has invalid local %q; must be pass or answer", local)
+	}
+	if localResponse, ok := options["local_response"].(string); ok {
+		config.LocalResponse = []byte(localResponse)
+	}
+	if config.HeaderTimeout, err = params.Duration(options, "header_timeout"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid header_timeout: %w", err)
+	}
+	if config.MaxPending, err = params.Int(options, "max_pending"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid max_pending: %w", err)
+	}
+	if config.MaxPendingPerIP, err = params.Int(options, "max_pending_per_ip"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid max_pending_per_ip: %w", err)
+	}
+	if logErrors, err := params.Bool(options, "log_errors"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid log_errors: %w", err)
+	} else if logErrors {
+		config.ErrorLog = slog.Default()
+	}
+	if config.AcceptErrors, err = params.Bool(options, "accept_errors"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid accept_errors: %w", err)
+	}
+	if config.Lazy, err = params.Bool(options, "lazy"); err != nil {
+		return nil, fmt.Errorf("proxy listener has invalid lazy: %w", err)
+	}
+	switch atLimit, _ := options["at_limit"].(string); atLimit {
+	case "", "refuse":
+		config.AtLimit = proxy.RefuseNew
+	case "drop_oldest":
+		config.AtLimit = proxy.DropOldest
+	default:
+		return nil, fmt.Errorf("proxy listener has invalid at_limit %q; must be refuse or drop_oldest", atLimit)
+	}
+
 	var inner net.Listener
-	var err error
 	if arg != "" {
+		if versionString, innerSpec, ok := strings.Cut(arg, ":"); ok {
+			if versions, ok := parseProxyVersions(versionString); ok {
+				config.Versions, arg = versions, innerSpec
+			}
+		}
 		inner, err = Open(arg)
-	} else if spec, ok := params["listener"].(map[string]interface{}); ok {
+	} else if spec, ok := options["listener"].(map[string]interface{}); ok {
 		inner, err = OpenJSON(spec)
 	} else {
 		return nil, errors.New("inner socket not specified for proxy listener")
@@ -171,5 +316,5 @@ func openProxyListener(params map[string]interface{}, arg string) (net.Listener,
 	if err != nil {
 		return nil, err
 	}
-	return proxy.NewListener(inner), nil
+	return config.NewListener(inner), nil
 }
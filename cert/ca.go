@@ -0,0 +1,120 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A function that returns a pool of CA certificates, such as for verifying
+// client certificates
+type GetCertPoolFunc func() (*x509.CertPool, error)
+
+// Load a pool of CA certificates from the given PEM-encoded file, which
+// must contain at least one CERTIFICATE block.  Other blocks are ignored.
+func LoadCertPool(filename string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	n, err := appendCertsFromFile(pool, filename)
+	if err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, errors.New("doesn't contain any certificates")
+	}
+	return pool, nil
+}
+
+// Load a pool of CA certificates from the PEM-encoded files in the given
+// directory.  Files whose names begin with a dot, and files which don't
+// contain any CERTIFICATE blocks, are ignored.
+func LoadCertPoolFromDirectory(path string) (*x509.CertPool, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	var total int
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || entry.IsDir() {
+			continue
+		}
+		n, err := appendCertsFromFile(pool, filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		total += n
+	}
+	if total == 0 {
+		return nil, errors.New("doesn't contain any certificates")
+	}
+	return pool, nil
+}
+
+// Add the certificates in the given PEM-encoded file to pool, returning
+// the number added
+func appendCertsFromFile(pool *x509.CertPool, filename string) (int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return 0, fmt.Errorf("contains invalid certificate: %w", err)
+			}
+			pool.AddCert(cert)
+			n++
+		}
+		data = rest
+	}
+	return n, nil
+}
+
+// Return a [GetCertPoolFunc] that gets the CA certificates from the given
+// file.  The file is reloaded automatically when it changes.  See the
+// documentation of [LoadCertPool] for the required format of the file.
+func GetCertPoolFromFile(filename string) GetCertPoolFunc {
+	return newReloader(filename, LoadCertPool).get
+}
+
+// Return a [GetCertPoolFunc] that gets the CA certificates from the files in
+// the given directory.  The certificates are reloaded automatically when a
+// file is added, removed, or changed.  See the documentation of
+// [LoadCertPoolFromDirectory] for details.
+func GetCertPoolFromDirectory(path string) GetCertPoolFunc {
+	return newReloader(path, LoadCertPoolFromDirectory).get
+}
@@ -27,58 +27,47 @@ package cert
 
 import (
 	"crypto/tls"
-	"os"
 	"sync"
 	"time"
 )
 
-type cachedFile struct {
-	*tls.Certificate
-	modTime time.Time
-}
-
-func (c *cachedFile) isFresh(latestModTime time.Time) bool {
-	return c.Certificate != nil && c.modTime.Equal(latestModTime)
-}
-
+// Caches certificates loaded from files, reloading each one when its file
+// changes
 type fileCache struct {
 	mu    sync.RWMutex
-	certs map[string]cachedFile
+	certs map[string]*reloader[*tls.Certificate]
 }
 
 func newFileCache() *fileCache {
 	return &fileCache{
-		certs: make(map[string]cachedFile),
+		certs: make(map[string]*reloader[*tls.Certificate]),
 	}
 }
 
-func (c *fileCache) get(filename string) cachedFile {
+// Load the certificate from filename, reusing the cached copy if the file
+// hasn't changed.  A file is only added to the cache once it has been loaded
+// successfully, so looking up files which don't exist (e.g. because their
+// names are derived from a client's SNI) doesn't grow the cache.
+func (c *fileCache) Load(filename string) (*tls.Certificate, error) {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.certs[filename]
-}
-
-func (c *fileCache) add(filename string, cert cachedFile) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.certs[filename] = cert
-}
+	r := c.certs[filename]
+	c.mu.RUnlock()
+	if r != nil {
+		return r.get()
+	}
 
-func (c *fileCache) Load(filename string) (*tls.Certificate, error) {
-	fileinfo, err := os.Stat(filename)
+	r = newReloader(filename, LoadCertificate)
+	cert, err := r.get()
 	if err != nil {
 		return nil, err
 	}
-	modTime := fileinfo.ModTime()
 
-	if cachedFile := c.get(filename); cachedFile.isFresh(modTime) {
-		return cachedFile.Certificate, nil
-	}
-	cert, err := LoadCertificate(filename)
-	if err != nil {
-		return nil, err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing := c.certs[filename]; existing != nil {
+		return existing.get()
 	}
-	c.add(filename, cachedFile{Certificate: cert, modTime: modTime})
+	c.certs[filename] = r
 	return cert, nil
 }
 
@@ -87,8 +76,8 @@ func (c *fileCache) Clean() {
 	defer c.mu.Unlock()
 
 	now := time.Now()
-	for filename, cert := range c.certs {
-		if now.After(cert.Leaf.NotAfter) {
+	for filename, r := range c.certs {
+		if cert, ok := r.cached(); !ok || now.After(cert.Leaf.NotAfter) {
 			delete(c.certs, filename)
 		}
 	}
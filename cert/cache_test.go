@@ -0,0 +1,124 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Write a self-signed certificate and its private key to path in the
+// format accepted by LoadCertificate
+func writeTestCertificate(t *testing.T, path string, dnsName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})...)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileCacheMissingFiles(t *testing.T) {
+	dir := &directory{Path: t.TempDir(), Cache: newFileCache()}
+	writeTestCertificate(t, filepath.Join(dir.Path, "www.example.com.pem"), "www.example.com")
+
+	for i := 0; i < 100; i++ {
+		hello := &tls.ClientHelloInfo{
+			ServerName:        fmt.Sprintf("missing%d.example.org", i),
+			CipherSuites:      []uint16{tls.TLS_AES_128_GCM_SHA256},
+			SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256, tls.PSSWithSHA256},
+			SupportedVersions: []uint16{tls.VersionTLS13},
+		}
+		if _, err := dir.GetCertificate(hello); err == nil {
+			t.Fatalf("GetCertificate succeeded for %s", hello.ServerName)
+		}
+	}
+	if n := len(dir.Cache.certs); n != 0 {
+		t.Fatalf("cache contains %d entries after looking up missing files, want 0", n)
+	}
+
+	hello := &tls.ClientHelloInfo{
+		ServerName:       "www.example.com",
+		CipherSuites:     []uint16{tls.TLS_AES_128_GCM_SHA256},
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+	}
+	for i := 0; i < 2; i++ {
+		cert, err := dir.GetCertificate(hello)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert.Leaf == nil || cert.Leaf.Subject.CommonName != "www.example.com" {
+			t.Fatalf("GetCertificate returned the wrong certificate")
+		}
+	}
+	if n := len(dir.Cache.certs); n != 1 {
+		t.Fatalf("cache contains %d entries after loading one file, want 1", n)
+	}
+}
+
+func TestFileCacheInvalidFile(t *testing.T) {
+	cache := newFileCache()
+	path := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Load(path); err == nil {
+		t.Fatal("Load succeeded for an invalid file")
+	}
+	if n := len(cache.certs); n != 0 {
+		t.Fatalf("cache contains %d entries after failing to load a file, want 0", n)
+	}
+}
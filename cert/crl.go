@@ -0,0 +1,80 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// A function that returns a list of certificate revocation lists
+type GetRevocationListsFunc func() ([]*x509.RevocationList, error)
+
+// Load certificate revocation lists from the given file, which must
+// contain either one DER-encoded CRL or any number of PEM-encoded X509 CRL
+// blocks.  The CRLs' signatures are not verified.
+func LoadRevocationLists(filename string) ([]*x509.RevocationList, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode(data); block == nil {
+		crl, err := x509.ParseRevocationList(data)
+		if err != nil {
+			return nil, fmt.Errorf("contains invalid CRL: %w", err)
+		}
+		return []*x509.RevocationList{crl}, nil
+	}
+
+	var crls []*x509.RevocationList
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "X509 CRL" {
+			return nil, errors.New("contains unrecognized PEM block `" + block.Type + "'")
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("contains invalid CRL: %w", err)
+		}
+		crls = append(crls, crl)
+		data = rest
+	}
+	return crls, nil
+}
+
+// Return a [GetRevocationListsFunc] that gets the CRLs from the given file.
+// The file is reloaded automatically when it changes.  See the
+// documentation of [LoadRevocationLists] for the required format of the file.
+func GetRevocationListsFromFile(filename string) GetRevocationListsFunc {
+	return newReloader(filename, LoadRevocationLists).get
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package cert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How often a reloader checks whether its file or directory has changed
+const reloadInterval = time.Second
+
+// Caches a value loaded from a file or directory, and reloads it when the
+// file or directory changes.  The file is checked at most once per
+// reloadInterval.  If the file has changed but can't be loaded (e.g.
+// because it is partially written), the last good value remains in use
+// until the next check.
+type reloader[T any] struct {
+	path    string
+	load    func(string) (T, error)
+	discard func(T) // if non-nil, called with values which have been replaced
+
+	mu      sync.Mutex // held while checking and reloading
+	current atomic.Pointer[loadedValue[T]]
+}
+
+type loadedValue[T any] struct {
+	version string
+	value   T
+	checked time.Time
+}
+
+func newReloader[T any](path string, load func(string) (T, error)) *reloader[T] {
+	return &reloader[T]{path: path, load: load}
+}
+
+func (r *reloader[T]) get() (T, error) {
+	if current := r.current.Load(); current != nil && time.Since(current.checked) < reloadInterval {
+		return current.value, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.current.Load()
+	if current != nil && time.Since(current.checked) < reloadInterval {
+		return current.value, nil
+	}
+	now := time.Now()
+	version, err := fileVersion(r.path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if current != nil && current.version == version {
+		r.current.Store(&loadedValue[T]{version: version, value: current.value, checked: now})
+		return current.value, nil
+	}
+	value, err := r.load(r.path)
+	if err != nil && current != nil {
+		r.current.Store(&loadedValue[T]{version: current.version, value: current.value, checked: now})
+		return current.value, nil
+	} else if err != nil {
+		return value, err
+	}
+	r.current.Store(&loadedValue[T]{version: version, value: value, checked: now})
+	if r.discard != nil && current != nil {
+		r.discard(current.value)
+	}
+	return value, nil
+}
+
+// Return the most recently loaded value, and whether there is one, without
+// checking the file
+func (r *reloader[T]) cached() (T, bool) {
+	if current := r.current.Load(); current != nil {
+		return current.value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Return a string which changes whenever the file at path is modified, or,
+// if path is a directory, whenever a file in the directory is added,
+// removed, or modified
+func fileVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%d %d", info.ModTime().UnixNano(), info.Size()), nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	var version strings.Builder
+	fmt.Fprintf(&version, "%d", info.ModTime().UnixNano())
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(path, entry.Name()))
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&version, "\x00%s %d %d", entry.Name(), info.ModTime().UnixNano(), info.Size())
+	}
+	return version.String(), nil
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package cert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// A function that returns TLS session ticket keys, for use with
+// [crypto/tls.Config.SetSessionTicketKeys]
+type GetSessionTicketKeysFunc func() ([][32]byte, error)
+
+// Load TLS session ticket keys from the given file, which must contain
+// one or more base64-encoded 32 byte keys, one per line.  The first key is
+// used to encrypt new tickets, and all keys are used to decrypt tickets,
+// so new keys should be added to the top of the file and old keys removed
+// from the bottom.  Blank lines and lines starting with # are ignored.
+//
+// To allow resumption across servers, give each server the same file.
+// A new key can be generated with `openssl rand -base64 32`.
+func LoadSessionTicketKeys(filename string) ([][32]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(data)
+
+	// Find the key lines first, so that keys can be allocated with its
+	// final length: growing it with append would leave copies of the keys
+	// in discarded backing arrays, where they can't be zeroed
+	var keyLines [][]byte
+	var lineNumbers []int
+	for i, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		keyLines = append(keyLines, line)
+		lineNumbers = append(lineNumbers, i+1)
+	}
+	if len(keyLines) == 0 {
+		return nil, errors.New("doesn't contain any keys")
+	}
+
+	keys := make([][32]byte, len(keyLines))
+	for i, line := range keyLines {
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+		n, err := base64.StdEncoding.Decode(decoded, line)
+		copy(keys[i][:], decoded)
+		clear(decoded)
+		if err != nil || n != len(keys[i]) {
+			clearKeys(keys)
+			return nil, fmt.Errorf("line %d: not a base64-encoded 32 byte key", lineNumbers[i])
+		}
+	}
+	return keys, nil
+}
+
+func clearKeys(keys [][32]byte) {
+	for i := range keys {
+		clear(keys[i][:])
+	}
+}
+
+// Return a [GetSessionTicketKeysFunc] that gets the keys from the given
+// file.  The file is reloaded automatically when it changes, and the old
+// keys are zeroed; callers must not retain them after the next call.  See
+// the documentation of [LoadSessionTicketKeys] for the required format of
+// the file.
+func GetSessionTicketKeysFromFile(filename string) GetSessionTicketKeysFunc {
+	r := newReloader(filename, LoadSessionTicketKeys)
+	r.discard = clearKeys
+	return r.get
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package cert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Return a key whose bytes are all b, and its base64 encoding
+func testTicketKey(b byte) ([32]byte, string) {
+	var key [32]byte
+	for i := range key {
+		key[i] = b
+	}
+	return key, base64.StdEncoding.EncodeToString(key[:])
+}
+
+func writeTicketKeys(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ticket_keys")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSessionTicketKeys(t *testing.T) {
+	key1, encoded1 := testTicketKey(1)
+	key2, encoded2 := testTicketKey(2)
+	key3, encoded3 := testTicketKey(3)
+
+	path := writeTicketKeys(t, "# newest key first\n"+encoded3+"\r\n\n  "+encoded2+"  \n#"+encoded1+"\n"+encoded1)
+	keys, err := LoadSessionTicketKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][32]byte{key3, key2, key1}; len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] || keys[2] != want[2] {
+		t.Errorf("LoadSessionTicketKeys returned %x; want %x", keys, want)
+	}
+	if cap(keys) != len(keys) {
+		t.Errorf("LoadSessionTicketKeys returned a slice with capacity %d; want %d", cap(keys), len(keys))
+	}
+}
+
+func TestLoadSessionTicketKeysInvalid(t *testing.T) {
+	_, encoded := testTicketKey(1)
+	tests := []struct {
+		contents string
+		wantErr  string
+	}{
+		{"", "doesn't contain any keys"},
+		{"# no keys\n\n", "doesn't contain any keys"},
+		{encoded + "\n" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 31)) + "\n", "line 2: not a base64-encoded 32 byte key"},
+		{base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 33)) + "\n", "line 1: not a base64-encoded 32 byte key"},
+		{"\n" + encoded + "\n\n" + strings.Repeat("!", 44) + "\n", "line 4: not a base64-encoded 32 byte key"},
+	}
+	for _, test := range tests {
+		keys, err := LoadSessionTicketKeys(writeTicketKeys(t, test.contents))
+		if err == nil {
+			t.Errorf("LoadSessionTicketKeys(%q) returned %d keys; want error", test.contents, len(keys))
+		} else if err.Error() != test.wantErr {
+			t.Errorf("LoadSessionTicketKeys(%q) returned error %q; want %q", test.contents, err, test.wantErr)
+		}
+	}
+}
+
+func TestGetSessionTicketKeysFromFileZeroesOldKeys(t *testing.T) {
+	key1, encoded1 := testTicketKey(1)
+	key2, encoded2 := testTicketKey(2)
+	path := writeTicketKeys(t, encoded1+"\n")
+	getKeys := GetSessionTicketKeysFromFile(path)
+	oldKeys, err := getKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldKeys[0] != key1 {
+		t.Fatalf("getKeys returned %x; want %x", oldKeys[0], key1)
+	}
+
+	if err := os.WriteFile(path, []byte(encoded2+"\n"+encoded1+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(reloadInterval + 100*time.Millisecond)
+	newKeys, err := getKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newKeys) != 2 || newKeys[0] != key2 || newKeys[1] != key1 {
+		t.Errorf("getKeys returned %x after the file changed", newKeys)
+	}
+	if oldKeys[0] != ([32]byte{}) {
+		t.Errorf("old keys were not zeroed after being replaced")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+// Package params parses the parameters of listener specs.
+package params // import "src.agwa.name/go-listener/internal/params"
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Return the parameter with the given name as a list of strings.  A single
+// string is treated as a list with one element.
+func Strings(params map[string]interface{}, name string) ([]string, error) {
+	switch value := params[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{value}, nil
+	case []string:
+		return value, nil
+	case []interface{}:
+		strs := make([]string, len(value))
+		for i := range value {
+			switch elem := value[i].(type) {
+			case string:
+				strs[i] = elem
+			case json.Number:
+				strs[i] = string(elem)
+			default:
+				return nil, fmt.Errorf("%s must contain only strings", name)
+			}
+		}
+		return strs, nil
+	default:
+		return nil, fmt.Errorf("%s must be a string or a list of strings", name)
+	}
+}
+
+// Return the parameter with the given name as a list of integers.
+func Ints(params map[string]interface{}, name string) ([]int, error) {
+	var strs []string
+	switch value := params[name].(type) {
+	case json.Number:
+		strs = []string{string(value)}
+	default:
+		var err error
+		if strs, err = Strings(params, name); err != nil {
+			return nil, err
+		}
+	}
+	ints := make([]int, len(strs))
+	for i, str := range strs {
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, fmt.Errorf("%s has invalid value %q: must be an integer", name, str)
+		}
+		ints[i] = n
+	}
+	return ints, nil
+}
+
+// Return the parameter with the given name as a boolean.  A missing
+// parameter is false.
+func Bool(params map[string]interface{}, name string) (bool, error) {
+	switch value := params[name].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return value, nil
+	case string:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("%s has invalid value %q: must be true or false", name, value)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("%s must be a boolean", name)
+	}
+}
+
+// Return the parameter with the given name as an integer.  A missing
+// parameter is zero.
+func Int(params map[string]interface{}, name string) (int, error) {
+	ints, err := Ints(params, name)
+	if err != nil {
+		return 0, err
+	} else if len(ints) > 1 {
+		return 0, fmt.Errorf("%s must not be specified more than once", name)
+	} else if len(ints) == 0 {
+		return 0, nil
+	}
+	return ints[0], nil
+}
+
+// Return the parameter with the given name as a duration (e.g. "30s").
+// A missing parameter is zero.
+func Duration(params map[string]interface{}, name string) (time.Duration, error) {
+	switch value := params[name].(type) {
+	case nil:
+		return 0, nil
+	case string:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s has invalid value %q: must be a duration such as 30s", name, value)
+		}
+		return duration, nil
+	default:
+		return 0, fmt.Errorf("%s must be a string", name)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package params
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    []string
+		wantErr bool
+	}{
+		{value: nil, want: nil},
+		{value: "a", want: []string{"a"}},
+		{value: []string{"a", "b"}, want: []string{"a", "b"}},
+		{value: []interface{}{"a", json.Number("2")}, want: []string{"a", "2"}},
+		{value: []interface{}{"a", true}, wantErr: true},
+		{value: true, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Strings(map[string]interface{}{"opt": test.value}, "opt")
+		if test.wantErr != (err != nil) || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Strings(%#v) = %#v, %v; want %#v, error=%v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestInts(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    []int
+		wantErr bool
+	}{
+		{value: nil, want: []int{}},
+		{value: "1", want: []int{1}},
+		{value: json.Number("2"), want: []int{2}},
+		{value: []interface{}{"1", json.Number("2")}, want: []int{1, 2}},
+		{value: "x", wantErr: true},
+		{value: []interface{}{"1", "x"}, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Ints(map[string]interface{}{"opt": test.value}, "opt")
+		if test.wantErr != (err != nil) || (!test.wantErr && !reflect.DeepEqual(got, test.want)) {
+			t.Errorf("Ints(%#v) = %#v, %v; want %#v, error=%v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestBool(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    bool
+		wantErr bool
+	}{
+		{value: nil, want: false},
+		{value: true, want: true},
+		{value: "true", want: true},
+		{value: "false", want: false},
+		{value: "yes", wantErr: true},
+		{value: []interface{}{"true", "true"}, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Bool(map[string]interface{}{"opt": test.value}, "opt")
+		if test.wantErr != (err != nil) || got != test.want {
+			t.Errorf("Bool(%#v) = %v, %v; want %v, error=%v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    int
+		wantErr bool
+	}{
+		{value: nil, want: 0},
+		{value: "10", want: 10},
+		{value: json.Number("-1"), want: -1},
+		{value: "ten", wantErr: true},
+		{value: []interface{}{"1", "2"}, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Int(map[string]interface{}{"opt": test.value}, "opt")
+		if test.wantErr != (err != nil) || got != test.want {
+			t.Errorf("Int(%#v) = %v, %v; want %v, error=%v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: nil, want: 0},
+		{value: "30s", want: 30 * time.Second},
+		{value: "1m30s", want: 90 * time.Second},
+		{value: "30", wantErr: true},
+		{value: json.Number("30"), wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Duration(map[string]interface{}{"opt": test.value}, "opt")
+		if test.wantErr != (err != nil) || got != test.want {
+			t.Errorf("Duration(%#v) = %v, %v; want %v, error=%v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	// A nil map, as returned for specs without options, has no parameters
+	var params map[string]interface{}
+	if strs, err := Strings(params, "opt"); strs != nil || err != nil {
+		t.Errorf("Strings = %v, %v", strs, err)
+	}
+	if b, err := Bool(params, "opt"); b || err != nil {
+		t.Errorf("Bool = %v, %v", b, err)
+	}
+	if n, err := Int(params, "opt"); n != 0 || err != nil {
+		t.Errorf("Int = %v, %v", n, err)
+	}
+}
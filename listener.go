@@ -31,10 +31,13 @@ import (
 )
 
 // A function that is called by [Open] or [OpenJSON] to create a [net.Listener] of
-// a particular type.  If called by Open, then the first argument is nil,
+// a particular type.  If called by Open, then the first argument contains the
+// options specified after the listener type (or is nil if there are none),
 // and the second argument is the string passed to Open, with the listener
-// type and colon character removed.  If called by OpenJSON, the first argument
-// is the JSON object passed to OpenJSON, and the second argument is empty.
+// type, options, and colon character removed.  Option values are strings,
+// except that the values of repeated options are collected into a []interface{}.
+// If called by OpenJSON, the first argument is the JSON object passed to OpenJSON,
+// and the second argument is empty.
 //
 // You only need to care about this if you are extending go-listener with
 // your own custom listener types using [RegisterListenerType].
@@ -41,12 +41,72 @@ func openType(listenerType string, params map[string]interface{}, argument strin
 	return openListener(params, argument)
 }
 
+// Split a string of the form TYPE[,OPTION[=VALUE]...]:ARGUMENT.  Options are
+// returned as a map, with the values of repeated options collected into a
+// []interface{}, and options without a value set to "true".  Square brackets
+// may be used to enclose colons within option values (e.g. IPv6 addresses);
+// the brackets are removed.  If there are no options, the returned map is nil.
+func splitSpec(spec string) (string, map[string]interface{}, string, error) {
+	var (
+		depth  int
+		colon  = -1
+		fields []string
+		field  strings.Builder
+	)
+	for i := 0; i < len(spec) && colon == -1; i++ {
+		switch c := spec[i]; {
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case c == ':' && depth == 0:
+			colon = i
+		case c == ',' && depth == 0:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	if colon == -1 {
+		return "", nil, "", errors.New("listener spec is missing a colon")
+	}
+	fields = append(fields, field.String())
+
+	listenerType, arg := fields[0], spec[colon+1:]
+	if len(fields) == 1 {
+		return listenerType, nil, arg, nil
+	}
+
+	options := make(map[string]interface{})
+	for _, option := range fields[1:] {
+		key, value, hasValue := strings.Cut(option, "=")
+		if key == "" {
+			return "", nil, "", fmt.Errorf("%s listener has an option without a name", listenerType)
+		}
+		if !hasValue {
+			value = "true"
+		}
+		switch existing := options[key].(type) {
+		case nil:
+			options[key] = value
+		case string:
+			options[key] = []interface{}{existing, value}
+		case []interface{}:
+			options[key] = append(existing, value)
+		}
+	}
+	return listenerType, options, arg, nil
+}
+
 // Open a listener with the given string notation
 func Open(spec string) (net.Listener, error) {
 	if strings.Contains(spec, ":") {
-		fields := strings.SplitN(spec, ":", 2)
-		listenerType, arg := fields[0], fields[1]
-		return openType(listenerType, nil, arg)
+		listenerType, options, arg, err := splitSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		return openType(listenerType, options, arg)
 	} else {
 		return openTCPListener(nil, spec)
 	}
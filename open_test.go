@@ -0,0 +1,84 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package listener
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		typ     string
+		options map[string]interface{}
+		arg     string
+		wantErr bool
+	}{
+		{spec: "tcp:443", typ: "tcp", arg: "443"},
+		{spec: "proxy:tcp:443", typ: "proxy", arg: "tcp:443"},
+		{spec: "unix,mode=0660:/run/app.sock", typ: "unix", options: map[string]interface{}{"mode": "0660"}, arg: "/run/app.sock"},
+		{spec: "proxy,optional:tcp:443", typ: "proxy", options: map[string]interface{}{"optional": "true"}, arg: "tcp:443"},
+		{spec: "proxy,local_response=:tcp:443", typ: "proxy", options: map[string]interface{}{"local_response": ""}, arg: "tcp:443"},
+		{
+			spec:    "proxy,trusted=10.0.0.0/8,trusted=192.168.0.0/16:tcp:443",
+			typ:     "proxy",
+			options: map[string]interface{}{"trusted": []interface{}{"10.0.0.0/8", "192.168.0.0/16"}},
+			arg:     "tcp:443",
+		},
+		{
+			spec:    "unix,allow_uid=1,allow_uid=2,allow_uid=3,lock:/run/app.sock",
+			typ:     "unix",
+			options: map[string]interface{}{"allow_uid": []interface{}{"1", "2", "3"}, "lock": "true"},
+			arg:     "/run/app.sock",
+		},
+		{spec: "tcp,bind=[::1]:443", typ: "tcp", options: map[string]interface{}{"bind": "::1"}, arg: "443"},
+		{spec: "proxy,trusted=[2001:db8::/32]:tcp:443", typ: "proxy", options: map[string]interface{}{"trusted": "2001:db8::/32"}, arg: "tcp:443"},
+		{spec: "tls,alpn=[h2,http/1.1]:/cert.pem:tcp:443", typ: "tls", options: map[string]interface{}{"alpn": "h2,http/1.1"}, arg: "/cert.pem:tcp:443"},
+		{spec: "proxy,local_response=[OK: up, 1=1]:tcp:443", typ: "proxy", options: map[string]interface{}{"local_response": "OK: up, 1=1"}, arg: "tcp:443"},
+		{spec: "proxy,local_response=[a=b]:tcp:443", typ: "proxy", options: map[string]interface{}{"local_response": "a=b"}, arg: "tcp:443"},
+		{spec: "tcp", wantErr: true},
+		{spec: "tcp,bind=[::1:443", wantErr: true},
+		{spec: "proxy,=x:tcp:443", wantErr: true},
+		{spec: "proxy,:tcp:443", wantErr: true},
+	}
+	for _, test := range tests {
+		typ, options, arg, err := splitSpec(test.spec)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("splitSpec(%q) succeeded; want error", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitSpec(%q) failed: %s", test.spec, err)
+			continue
+		}
+		if typ != test.typ || arg != test.arg || !reflect.DeepEqual(options, test.options) {
+			t.Errorf("splitSpec(%q) = %q, %#v, %q; want %q, %#v, %q", test.spec, typ, options, arg, test.typ, test.options, test.arg)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func benchmarkAccept(b *testing.B, config Config) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	listener := config.NewListener(inner)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.RemoteAddr()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	header := &Header{
+		Version:    2,
+		RemoteAddr: net.TCPAddrFromAddrPort(netip.MustParseAddrPort("192.0.2.1:12345")),
+		LocalAddr:  net.TCPAddrFromAddrPort(netip.MustParseAddrPort("198.51.100.1:443")),
+	}
+	request := append(header.Format(), 'x')
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		response := make([]byte, 1)
+		for pb.Next() {
+			conn, err := net.Dial("tcp", inner.Addr().String())
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			if _, err := conn.Write(request); err != nil {
+				b.Error(err)
+			} else if _, err := io.ReadFull(conn, response); err != nil {
+				b.Error(err)
+			}
+			conn.Close()
+		}
+	})
+}
+
+func BenchmarkAcceptEager(b *testing.B) {
+	benchmarkAccept(b, Config{})
+}
+
+func BenchmarkAcceptLazy(b *testing.B) {
+	benchmarkAccept(b, Config{Lazy: true})
+}
@@ -31,18 +31,59 @@ import (
 
 type proxyConn struct {
 	net.Conn
-	localAddr  net.Addr
-	remoteAddr net.Addr
+	header *Header
+}
+
+// Return the PROXY protocol header read from conn, or nil if conn was
+// not accepted from a PROXY protocol listener.  If conn is not itself such
+// a connection, ConnHeader follows the chain of NetConn methods, like the
+// one provided by [crypto/tls.Conn].
+func ConnHeader(conn net.Conn) *Header {
+	for {
+		switch c := conn.(type) {
+		case interface{ ProxyHeader() *Header }:
+			return c.ProxyHeader()
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil
+		}
+	}
+}
+
+// Return the PROXY protocol header read from the connection
+func (conn *proxyConn) ProxyHeader() *Header {
+	return conn.header
 }
 
 func (conn *proxyConn) LocalAddr() net.Addr {
-	return conn.localAddr
+	return conn.header.LocalAddr
 }
 
 func (conn *proxyConn) RemoteAddr() net.Addr {
-	return conn.remoteAddr
+	return conn.header.RemoteAddr
 }
 
 func (conn *proxyConn) NetConn() net.Conn {
 	return conn.Conn
 }
+
+// A connection from which prefix has already been read.  Read returns
+// prefix before reading from the underlying connection.
+type prefixedConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (conn *prefixedConn) Read(p []byte) (int, error) {
+	if len(conn.prefix) > 0 {
+		n := copy(p, conn.prefix)
+		conn.prefix = conn.prefix[n:]
+		return n, nil
+	}
+	return conn.Conn.Read(p)
+}
+
+func (conn *prefixedConn) NetConn() net.Conn {
+	return conn.Conn
+}
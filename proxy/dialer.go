@@ -0,0 +1,127 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ContextDialer is implemented by [net.Dialer], [golang.org/x/net/proxy.ContextDialer],
+// and other dialers that support contexts
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Dialer makes outbound connections which begin with a PROXY protocol
+// header, for use when relaying connections to a server that is behind
+// a PROXY protocol listener.
+type Dialer struct {
+	// The dialer used to make the underlying connection.  If nil, a
+	// zero [net.Dialer] is used.
+	Dialer ContextDialer
+
+	// The version of the PROXY protocol to send (1 or 2).  If zero,
+	// version 2 is sent.  Version 1 cannot convey TLVs or non-TCP
+	// addresses; see [Header.FormatV1].
+	Version int
+}
+
+// Connect to address on the named network, as with [net.Dial], and write
+// header to the connection before returning it
+func (dialer *Dialer) Dial(network, address string, header *Header) (net.Conn, error) {
+	return dialer.DialContext(context.Background(), network, address, header)
+}
+
+// Connect to address on the named network using ctx, as with
+// [net.Dialer.DialContext], and write header to the connection before
+// returning it.  An error is returned without connecting if header is not
+// valid according to [Header.Validate].  The header is written while ctx
+// is in effect, so a context deadline also bounds the time spent writing
+// the header.
+func (dialer *Dialer) DialContext(ctx context.Context, network, address string, header *Header) (net.Conn, error) {
+	if err := header.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid PROXY header: %w", err)
+	}
+	var formatted []byte
+	switch dialer.Version {
+	case 0, 2:
+		formatted = header.Format()
+	case 1:
+		formatted = header.FormatV1()
+	default:
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", dialer.Version)
+	}
+
+	var inner ContextDialer = dialer.Dialer
+	if inner == nil {
+		inner = new(net.Dialer)
+	}
+	conn, err := inner.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetWriteDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	_, err = conn.Write(formatted)
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("writing PROXY header: %w", err)
+	}
+	if err := conn.SetWriteDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Return a header describing conn, for relaying conn to another server.
+// The header's remote address is the address of conn's peer and its local
+// address is the address on which conn was accepted.  If conn was itself
+// accepted from a PROXY protocol listener, these are the addresses from
+// the original header, and the original header's TLVs are preserved.
+func HeaderFromConn(conn net.Conn) *Header {
+	header := &Header{
+		RemoteAddr: conn.RemoteAddr(),
+		LocalAddr:  conn.LocalAddr(),
+	}
+	if original := ConnHeader(conn); original != nil {
+		header.TLVs = original.TLVs
+	}
+	return header
+}
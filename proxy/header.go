@@ -30,14 +30,29 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"net"
+	"net/netip"
+	"strconv"
+	"strings"
 )
 
 var protocolSignature = [12]byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
 
 const protocolVersion = 2
 
+var v1Signature = []byte("PROXY ")
+
+const v1MaxLength = 107 // including CRLF
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+const unixAddressLength = 108
+
+// Maximum length of a version 2 header, excluding the 16 byte preamble
+const maxPayloadLength = 0xFFFF
+
 const (
 	commandLocal = 0x00
 	commandProxy = 0x01
@@ -49,49 +64,250 @@ const (
 	familyUDP4        = 0x12
 	familyTCP6        = 0x21
 	familyUDP6        = 0x22
+	familyUnixStream  = 0x31
+	familyUnixDgram   = 0x32
+)
+
+// A set of PROXY protocol versions
+type Versions uint8
+
+const (
+	Version1   Versions = 1 << 0
+	Version2   Versions = 1 << 1
+	AnyVersion Versions = Version1 | Version2
 )
 
 // Header represents a PROXY protocol header
 type Header struct {
+	// The version of the PROXY protocol used by the header (1 or 2).
+	// Set by [ReadHeader]; ignored by [Header.Format] and [Header.FormatV1].
+	Version int
+
+	// True if the header uses the LOCAL command, meaning the proxy made
+	// the connection on its own behalf (e.g. for a health check) rather
+	// than relaying it from a client.  RemoteAddr and LocalAddr are then
+	// the real addresses of the connection.  Version 1 headers cannot
+	// convey this; [Header.FormatV1] formats LOCAL headers as "PROXY UNKNOWN".
+	Local bool
+
 	RemoteAddr net.Addr
 	LocalAddr  net.Addr
+
+	// TLVs (type-length-value fields) from a version 2 header.  Methods
+	// such as [Header.ALPN] and [Header.SSL] decode well-known types.
+	TLVs []TLV
 }
 
-// Read the PROXY protocol header from conn
+// Read the PROXY protocol header from conn.  Both version 1 (text) and
+// version 2 (binary) headers are supported.
 func ReadHeader(conn net.Conn) (*Header, error) {
+	return readHeader(conn, AnyVersion)
+}
+
+func readHeader(conn net.Conn, versions Versions) (*Header, error) {
+	// Neither a version 1 nor a version 2 header can be shorter than 8 bytes,
+	// so it's safe to read this much without reading past the header
 	var preamble [16]byte
-	if _, err := io.ReadFull(conn, preamble[:]); err != nil {
+	if _, err := io.ReadFull(conn, preamble[:8]); err != nil {
 		return nil, err
 	}
 
+	if bytes.HasPrefix(preamble[:8], v1Signature) {
+		if versions&Version1 == 0 {
+			return nil, errors.New("PROXY protocol version 1 is not allowed")
+		}
+		return readV1Header(conn, preamble[:8])
+	}
+	if !bytes.Equal(preamble[:8], protocolSignature[:8]) {
+		return nil, errors.New("not a proxied connection")
+	}
+	if versions&Version2 == 0 {
+		return nil, errors.New("PROXY protocol version 2 is not allowed")
+	}
+
+	if _, err := io.ReadFull(conn, preamble[8:]); err != nil {
+		return nil, err
+	}
+	// Validate the preamble before reading the rest of the header, so
+	// that a bogus header doesn't cause up to 64KiB to be read
+	if err := checkV2Preamble(preamble[:]); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(preamble[14:16]))
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, err
+	}
+	return parseV2Header(preamble[:], payload, conn.LocalAddr(), conn.RemoteAddr())
+}
+
+// Parse a version 2 header comprising preamble (the first 16 bytes) and
+// payload (the rest of the header).  The header's addresses are set to
+// localAddr and remoteAddr if the header uses the LOCAL command.
+func parseV2Header(preamble []byte, payload []byte, localAddr, remoteAddr net.Addr) (*Header, error) {
+	if err := checkV2Preamble(preamble); err != nil {
+		return nil, err
+	}
+	var (
+		command = preamble[12] & 0xF
+		family  = preamble[13]
+	)
+
+	var header *Header
+	if command == commandLocal {
+		header = &Header{Local: true, LocalAddr: localAddr, RemoteAddr: remoteAddr}
+	} else {
+		var err error
+		if header, err = parseProxyHeader(family, payload); err != nil {
+			return nil, err
+		}
+	}
+	header.Version = 2
+
+	if addressLength := addressLength(family); addressLength <= len(payload) {
+		var err error
+		if header.TLVs, err = parseTLVs(payload[addressLength:]); err != nil {
+			return nil, err
+		}
+		if err := verifyChecksum(preamble, payload, header.TLVs); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+// Return an error if the signature, version, or command in the first 16
+// bytes of a version 2 header are invalid
+func checkV2Preamble(preamble []byte) error {
 	var (
 		signature = preamble[0:12]
 		version   = preamble[12] >> 4
 		command   = preamble[12] & 0xF
-		family    = preamble[13]
-		length    = binary.BigEndian.Uint16(preamble[14:16])
 	)
 
-	if !bytes.Equal(signature[:], protocolSignature[:]) {
-		return nil, errors.New("not a proxied connection")
+	if !bytes.Equal(signature, protocolSignature[:]) {
+		return errors.New("not a proxied connection")
 	}
 	if version != protocolVersion {
-		return nil, errors.New("unsupported proxy protocol version")
+		return errors.New("unsupported proxy protocol version")
+	}
+	if command != commandLocal && command != commandProxy {
+		return fmt.Errorf("unsupported proxy command %x", command)
+	}
+	return nil
+}
+
+// If tlvs contains a CRC32C TLV, verify that it matches the checksum of
+// the header comprising preamble and payload.  The TLV's value must be a
+// slice of payload.
+func verifyChecksum(preamble []byte, payload []byte, tlvs []TLV) error {
+	value, ok := findTLV(tlvs, TypeCRC32C)
+	if !ok {
+		return nil
+	}
+	if len(value) != 4 {
+		return errors.New("CRC32C TLV has wrong length")
 	}
+	expected := binary.BigEndian.Uint32(value)
 
-	payload := make([]byte, length)
-	if _, err := io.ReadFull(conn, payload); err != nil {
-		return nil, err
+	// The checksum is computed with the checksum field set to zero
+	saved := [4]byte(value)
+	clear(value)
+	checksum := crc32.Update(crc32.Checksum(preamble, crc32cTable), crc32cTable, payload)
+	copy(value, saved[:])
+
+	if checksum != expected {
+		return errors.New("header has incorrect CRC32C checksum")
+	}
+	return nil
+}
+
+// Return the length of the address block for the given family
+func addressLength(family uint8) int {
+	switch family {
+	case familyTCP4, familyUDP4:
+		return 12
+	case familyTCP6, familyUDP6:
+		return 36
+	case familyUnixStream, familyUnixDgram:
+		return 2 * unixAddressLength
+	default:
+		return 0
 	}
+}
 
-	switch command {
-	case commandLocal:
-		return &Header{LocalAddr: conn.LocalAddr(), RemoteAddr: conn.RemoteAddr()}, nil
-	case commandProxy:
-		return parseProxyHeader(family, payload)
+// Read the remainder of a version 1 header, which begins with prefix.
+// The header is read one byte at a time to avoid consuming any data
+// past the terminating CRLF.
+func readV1Header(conn net.Conn, prefix []byte) (*Header, error) {
+	line := make([]byte, len(prefix), v1MaxLength)
+	copy(line, prefix)
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == v1MaxLength {
+			return nil, errors.New("version 1 header is too long")
+		}
+		var b [1]byte
+		if _, err := io.ReadFull(conn, b[:]); err != nil {
+			return nil, err
+		}
+		line = append(line, b[0])
+	}
+	return parseV1Header(conn, string(line[:len(line)-2]))
+}
+
+func parseV1Header(conn net.Conn, line string) (*Header, error) {
+	fields := strings.Split(line, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		// Per the spec, the rest of the line is ignored and the real
+		// connection endpoints are used
+		return &Header{Version: 1, LocalAddr: conn.LocalAddr(), RemoteAddr: conn.RemoteAddr()}, nil
+	}
+	if len(fields) != 6 {
+		return nil, errors.New("malformed version 1 header")
+	}
+	var (
+		protocol   = fields[1]
+		remoteIP   = net.ParseIP(fields[2])
+		localIP    = net.ParseIP(fields[3])
+		remotePort = parseV1Port(fields[4])
+		localPort  = parseV1Port(fields[5])
+	)
+	if remoteIP == nil || localIP == nil {
+		return nil, errors.New("version 1 header contains invalid IP address")
+	}
+	if remotePort == -1 || localPort == -1 {
+		return nil, errors.New("version 1 header contains invalid port")
+	}
+	switch protocol {
+	case "TCP4":
+		if strings.Contains(fields[2], ":") || strings.Contains(fields[3], ":") {
+			return nil, errors.New("version 1 TCP4 header contains IPv6 address")
+		}
+		remoteIP, localIP = remoteIP.To4(), localIP.To4()
+	case "TCP6":
+		if !strings.Contains(fields[2], ":") || !strings.Contains(fields[3], ":") {
+			return nil, errors.New("version 1 TCP6 header contains IPv4 address")
+		}
 	default:
-		return nil, fmt.Errorf("unsupported proxy command %x", command)
+		return nil, fmt.Errorf("unsupported version 1 protocol %q", protocol)
 	}
+	return &Header{
+		Version:    1,
+		RemoteAddr: &net.TCPAddr{IP: remoteIP, Port: remotePort},
+		LocalAddr:  &net.TCPAddr{IP: localIP, Port: localPort},
+	}, nil
+}
+
+// Parse a port number from a version 1 header, returning -1 if invalid
+func parseV1Port(str string) int {
+	if str == "" || len(str) > 1 && str[0] == '0' {
+		return -1
+	}
+	port, err := strconv.ParseUint(str, 10, 16)
+	if err != nil {
+		return -1
+	}
+	return int(port)
 }
 
 func parseProxyHeader(family uint8, payload []byte) (*Header, error) {
@@ -152,33 +368,196 @@ func parseProxyHeader(family uint8, payload []byte) (*Header, error) {
 				Port: int(binary.BigEndian.Uint16(payload[34:36])),
 			},
 		}, nil
+	case familyUnixStream, familyUnixDgram:
+		if len(payload) < 2*unixAddressLength {
+			return nil, errors.New("header too short for UNIX domain socket")
+		}
+		network := "unix"
+		if family == familyUnixDgram {
+			network = "unixgram"
+		}
+		return &Header{
+			RemoteAddr: &net.UnixAddr{
+				Net:  network,
+				Name: parseUnixAddress(payload[0:unixAddressLength]),
+			},
+			LocalAddr: &net.UnixAddr{
+				Net:  network,
+				Name: parseUnixAddress(payload[unixAddressLength : 2*unixAddressLength]),
+			},
+		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported address family %x", family)
 	}
 }
 
-// Return the wire representation of header
+// Return an error if header can't be represented in a version 2 PROXY
+// header.  Unless header is a LOCAL header, RemoteAddr and LocalAddr must
+// both be nil, or have the same type; [Header.Format] falls back to an
+// unspecified address family for headers which don't satisfy this.  Each
+// TLV value, and the addresses and TLVs combined, must be no longer than
+// 65535 bytes.
+func (header Header) Validate() error {
+	if !header.Local {
+		if err := header.validateAddresses(); err != nil {
+			return err
+		}
+	}
+	return header.validateTLVs()
+}
+
+func (header Header) validateAddresses() error {
+	switch remoteAddr := header.RemoteAddr.(type) {
+	case nil:
+		if header.LocalAddr != nil {
+			return errors.New("header has LocalAddr but not RemoteAddr")
+		}
+	case *net.TCPAddr:
+		if localAddr, ok := header.LocalAddr.(*net.TCPAddr); !ok || localAddr == nil || remoteAddr == nil {
+			return fmt.Errorf("header has TCP RemoteAddr but LocalAddr of type %T", header.LocalAddr)
+		}
+	case *net.UDPAddr:
+		if localAddr, ok := header.LocalAddr.(*net.UDPAddr); !ok || localAddr == nil || remoteAddr == nil {
+			return fmt.Errorf("header has UDP RemoteAddr but LocalAddr of type %T", header.LocalAddr)
+		}
+	case *net.UnixAddr:
+		localAddr, ok := header.LocalAddr.(*net.UnixAddr)
+		if remoteAddr == nil || header.LocalAddr != nil && !ok {
+			return fmt.Errorf("header has UNIX RemoteAddr but LocalAddr of type %T", header.LocalAddr)
+		}
+		if len(remoteAddr.Name) > unixAddressLength || localAddr != nil && len(localAddr.Name) > unixAddressLength {
+			return errors.New("header has UNIX address longer than 108 bytes")
+		}
+	default:
+		return fmt.Errorf("header has unsupported address type %T", header.RemoteAddr)
+	}
+	return nil
+}
+
+func (header Header) validateTLVs() error {
+	length := 0
+	if !header.Local {
+		length = len(header.formatAddresses()) - 16
+	}
+	for _, tlv := range header.TLVs {
+		valueLength := len(tlv.Value)
+		if tlv.Type == TypeCRC32C {
+			valueLength = 4
+		}
+		if valueLength > maxPayloadLength {
+			return fmt.Errorf("TLV of type 0x%02x is longer than %d bytes", tlv.Type, maxPayloadLength)
+		}
+		length += 3 + valueLength
+	}
+	if length > maxPayloadLength {
+		return fmt.Errorf("header is longer than %d bytes", maxPayloadLength)
+	}
+	return nil
+}
+
+// Return the version 2 wire representation of header, including its
+// TLVs.  To include a checksum, add a TLV of type [TypeCRC32C] to the
+// header; its value is replaced with the CRC32C checksum of the header.
+// Format panics if the TLVs are too long to fit in a header; use
+// [Header.Validate] to check headers from untrusted sources.
 func (header Header) Format() []byte {
+	if header.Local {
+		return header.appendTLVs(formatLocalHeader())
+	}
+	return header.appendTLVs(header.formatAddresses())
+}
+
+// Append header's TLVs to buf, which contains a version 2 header without
+// TLVs, updating the length and computing the checksum if requested
+func (header Header) appendTLVs(buf []byte) []byte {
+	if len(header.TLVs) == 0 {
+		return buf
+	}
+	checksumOffset := -1
+	for _, tlv := range header.TLVs {
+		value := tlv.Value
+		if tlv.Type == TypeCRC32C {
+			checksumOffset = len(buf) + 3
+			value = make([]byte, 4)
+		}
+		buf = appendTLV(buf, tlv.Type, value)
+	}
+	if len(buf)-16 > maxPayloadLength {
+		panic("proxy: TLVs are too long to fit in a PROXY header")
+	}
+	binary.BigEndian.PutUint16(buf[14:16], uint16(len(buf)-16))
+	if checksumOffset != -1 {
+		binary.BigEndian.PutUint32(buf[checksumOffset:], crc32.Checksum(buf, crc32cTable))
+	}
+	return buf
+}
+
+func (header Header) formatAddresses() []byte {
 	switch remoteAddr := header.RemoteAddr.(type) {
 	case *net.TCPAddr:
-		localAddr := header.LocalAddr.(*net.TCPAddr)
-		if remoteAddr.IP.To4() != nil {
+		localAddr, ok := header.LocalAddr.(*net.TCPAddr)
+		if !ok || localAddr == nil || remoteAddr == nil {
+			return formatUnspecifiedHeader()
+		}
+		if remoteAddr.IP.To4() != nil && localAddr.IP.To4() != nil {
 			return formatIPv4Header(familyTCP4, remoteAddr.IP, localAddr.IP, remoteAddr.Port, localAddr.Port)
 		} else {
 			return formatIPv6Header(familyTCP6, remoteAddr.IP, localAddr.IP, remoteAddr.Port, localAddr.Port)
 		}
 	case *net.UDPAddr:
-		localAddr := header.LocalAddr.(*net.UDPAddr)
-		if remoteAddr.IP.To4() != nil {
+		localAddr, ok := header.LocalAddr.(*net.UDPAddr)
+		if !ok || localAddr == nil || remoteAddr == nil {
+			return formatUnspecifiedHeader()
+		}
+		if remoteAddr.IP.To4() != nil && localAddr.IP.To4() != nil {
 			return formatIPv4Header(familyUDP4, remoteAddr.IP, localAddr.IP, remoteAddr.Port, localAddr.Port)
 		} else {
 			return formatIPv6Header(familyUDP6, remoteAddr.IP, localAddr.IP, remoteAddr.Port, localAddr.Port)
 		}
+	case *net.UnixAddr:
+		localAddr, _ := header.LocalAddr.(*net.UnixAddr)
+		if localAddr == nil {
+			localAddr = new(net.UnixAddr)
+		}
+		if remoteAddr == nil {
+			return formatUnspecifiedHeader()
+		}
+		family := uint8(familyUnixStream)
+		if remoteAddr.Net == "unixgram" {
+			family = familyUnixDgram
+		}
+		if len(remoteAddr.Name) > unixAddressLength || len(localAddr.Name) > unixAddressLength {
+			return formatUnspecifiedHeader()
+		}
+		return formatUnixHeader(family, remoteAddr.Name, localAddr.Name)
 	default:
 		return formatUnspecifiedHeader()
 	}
 }
 
+// Return the version 1 (text) wire representation of header.  Version 1
+// only supports TCP over IPv4 and IPv6; other headers are formatted as
+// "PROXY UNKNOWN".
+func (header Header) FormatV1() []byte {
+	if header.Local {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+	remoteAddr, remoteOK := header.RemoteAddr.(*net.TCPAddr)
+	localAddr, localOK := header.LocalAddr.(*net.TCPAddr)
+	if !remoteOK || !localOK || remoteAddr == nil || localAddr == nil {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+	if remoteIP, localIP := remoteAddr.IP.To4(), localAddr.IP.To4(); remoteIP != nil && localIP != nil {
+		return fmt.Appendf(nil, "PROXY TCP4 %s %s %d %d\r\n", remoteIP, localIP, remoteAddr.Port, localAddr.Port)
+	}
+	remoteIP, remoteOK := netip.AddrFromSlice(remoteAddr.IP.To16())
+	localIP, localOK := netip.AddrFromSlice(localAddr.IP.To16())
+	if !remoteOK || !localOK {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+	return fmt.Appendf(nil, "PROXY TCP6 %s %s %d %d\r\n", remoteIP, localIP, remoteAddr.Port, localAddr.Port)
+}
+
 func formatIPv4Header(family uint8, remoteIP, localIP net.IP, remotePort, localPort int) []byte {
 	header := make([]byte, 28)
 	copy(header[0:12], protocolSignature[:])
@@ -198,13 +577,53 @@ func formatIPv6Header(family uint8, remoteIP, localIP net.IP, remotePort, localP
 	header[12] = (protocolVersion << 4) | commandProxy
 	header[13] = family
 	binary.BigEndian.PutUint16(header[14:16], 36)
-	copy(header[16:32], remoteIP)
-	copy(header[32:48], localIP)
+	copy(header[16:32], remoteIP.To16())
+	copy(header[32:48], localIP.To16())
 	binary.BigEndian.PutUint16(header[48:50], uint16(remotePort))
 	binary.BigEndian.PutUint16(header[50:52], uint16(localPort))
 	return header[:]
 }
 
+func formatUnixHeader(family uint8, remoteName, localName string) []byte {
+	header := make([]byte, 16+2*unixAddressLength)
+	copy(header[0:12], protocolSignature[:])
+	header[12] = (protocolVersion << 4) | commandProxy
+	header[13] = family
+	binary.BigEndian.PutUint16(header[14:16], 2*unixAddressLength)
+	formatUnixAddress(header[16:16+unixAddressLength], remoteName)
+	formatUnixAddress(header[16+unixAddressLength:], localName)
+	return header
+}
+
+// Parse a UNIX domain socket address from a version 2 header.  Pathname
+// addresses are terminated by a NUL byte.  Abstract addresses begin with a
+// NUL byte, and are returned with a leading @, as expected by package net.
+// Since the length of an abstract address is not conveyed by the header,
+// trailing NUL bytes are removed.  An address consisting entirely of NUL
+// bytes denotes an unnamed socket, and is returned as the empty string.
+func parseUnixAddress(addr []byte) string {
+	if len(addr) > 0 && addr[0] == 0 {
+		if name := bytes.TrimRight(addr[1:], "\x00"); len(name) > 0 {
+			return "@" + string(name)
+		}
+		return ""
+	}
+	if nul := bytes.IndexByte(addr, 0); nul != -1 {
+		addr = addr[:nul]
+	}
+	return string(addr)
+}
+
+// Format a UNIX domain socket address into buf, which is zero-filled and
+// unixAddressLength bytes long.  A leading @ denotes an abstract address.
+func formatUnixAddress(buf []byte, name string) {
+	if strings.HasPrefix(name, "@") {
+		copy(buf[1:], name[1:])
+	} else {
+		copy(buf, name)
+	}
+}
+
 func formatUnspecifiedHeader() []byte {
 	var header [16]byte
 	copy(header[0:12], protocolSignature[:])
@@ -212,3 +631,11 @@ func formatUnspecifiedHeader() []byte {
 	header[13] = familyUnspecified
 	return header[:]
 }
+
+func formatLocalHeader() []byte {
+	var header [16]byte
+	copy(header[0:12], protocolSignature[:])
+	header[12] = (protocolVersion << 4) | commandLocal
+	header[13] = familyUnspecified
+	return header[:]
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMismatchedAddresses(t *testing.T) {
+	remoteAddr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	tests := []struct {
+		name   string
+		header Header
+	}{
+		{"nil LocalAddr", Header{RemoteAddr: remoteAddr}},
+		{"UDP LocalAddr", Header{RemoteAddr: remoteAddr, LocalAddr: &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443}}},
+		{"nil RemoteAddr", Header{LocalAddr: remoteAddr}},
+		{"typed nil RemoteAddr", Header{RemoteAddr: (*net.TCPAddr)(nil), LocalAddr: remoteAddr}},
+		{"typed nil UNIX RemoteAddr", Header{RemoteAddr: (*net.UnixAddr)(nil)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.header.Validate(); err == nil {
+				t.Errorf("Validate succeeded")
+			}
+			if formatted := test.header.Format(); !bytes.Equal(formatted, formatUnspecifiedHeader()) {
+				t.Errorf("Format returned %x; want unspecified header", formatted)
+			}
+			if formatted := test.header.FormatV1(); string(formatted) != "PROXY UNKNOWN\r\n" {
+				t.Errorf("FormatV1 returned %q; want PROXY UNKNOWN", formatted)
+			}
+		})
+	}
+}
+
+func TestDialInvalidHeader(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+
+	header := &Header{RemoteAddr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}}
+	conn, err := new(Dialer).Dial("tcp", inner.Addr().String(), header)
+	if err == nil {
+		conn.Close()
+		t.Fatal("Dial succeeded with a header lacking LocalAddr")
+	}
+}
+
+// A TCP over IPv4 header with a CRC32C TLV, checksummed independently of
+// this package
+const checksummedHeader = "0d0a0d0a000d0a515549540a21110013c0000201c000020204d201bb030004c91d6693"
+
+func TestChecksum(t *testing.T) {
+	data, err := hex.DecodeString(checksummedHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := Header{
+		RemoteAddr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234},
+		LocalAddr:  &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443},
+		TLVs:       []TLV{{Type: TypeCRC32C}},
+	}
+	if formatted := header.Format(); !bytes.Equal(formatted, data) {
+		t.Errorf("Format returned %x; want %x", formatted, data)
+	}
+
+	if _, err := parseV2Header(data[:16], data[16:], nil, nil); err != nil {
+		t.Errorf("correct checksum rejected: %s", err)
+	}
+	for i := range data {
+		if i >= 12 && i < 16 || i == 28 {
+			// Corrupting the version, command, family, or length is
+			// detected before the checksum is verified, and corrupting
+			// the TLV type removes the checksum
+			continue
+		}
+		corrupted := bytes.Clone(data)
+		corrupted[i] ^= 0x01
+		if _, err := parseV2Header(corrupted[:16], corrupted[16:], nil, nil); err == nil {
+			t.Errorf("corruption of byte %d not detected", i)
+		}
+	}
+}
+
+func TestOversizedTLV(t *testing.T) {
+	remoteAddr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	localAddr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443}
+	tests := []struct {
+		name string
+		tlvs []TLV
+		ok   bool
+	}{
+		{"largest TLV", []TLV{{Type: TypeNoop, Value: make([]byte, 0xFFFF-12-3)}}, true},
+		{"TLV too long for header", []TLV{{Type: TypeNoop, Value: make([]byte, 0xFFFF-12-2)}}, false},
+		{"TLV too long", []TLV{{Type: TypeNoop, Value: make([]byte, 0x10000)}}, false},
+		{"TLVs too long", []TLV{{Type: TypeNoop, Value: make([]byte, 0x8000)}, {Type: TypeNoop, Value: make([]byte, 0x8000)}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := Header{RemoteAddr: remoteAddr, LocalAddr: localAddr, TLVs: test.tlvs}
+			err := header.Validate()
+			if test.ok {
+				if err != nil {
+					t.Fatalf("Validate failed: %s", err)
+				}
+				if formatted := header.Format(); len(formatted) != 16+0xFFFF {
+					t.Errorf("Format returned %d bytes", len(formatted))
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate succeeded")
+			}
+			defer func() {
+				if recover() == nil {
+					t.Error("Format did not panic")
+				}
+			}()
+			header.Format()
+		})
+	}
+
+	var dialer Dialer
+	header := &Header{RemoteAddr: remoteAddr, LocalAddr: localAddr, TLVs: []TLV{{Type: TypeNoop, Value: make([]byte, 0x10000)}}}
+	if _, err := dialer.Dial("tcp", "127.0.0.1:0", header); err == nil || !strings.Contains(err.Error(), "invalid PROXY header") {
+		t.Errorf("Dial returned %v; want invalid header error", err)
+	}
+}
+
+func TestReadHeaderValidatesPreamble(t *testing.T) {
+	tests := []struct {
+		name     string
+		preamble []byte
+	}{
+		{"bad version", []byte("\r\n\r\n\x00\r\nQUIT\n\x31\x11\xff\xff")},
+		{"bad command", []byte("\r\n\r\n\x00\r\nQUIT\n\x2f\x11\xff\xff")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The preamble claims a 64KiB payload which is never sent, so
+			// readHeader blocks unless it rejects the preamble first
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			go client.Write(test.preamble)
+			server.SetReadDeadline(time.Now().Add(5 * time.Second))
+			if _, err := ReadHeader(server); err == nil {
+				t.Fatal("ReadHeader succeeded")
+			} else if errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Fatal("ReadHeader read the payload before validating the preamble")
+			}
+		})
+	}
+}
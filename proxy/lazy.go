@@ -0,0 +1,163 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type lazyListener struct {
+	inner  net.Listener
+	config Config
+	closed atomic.Bool
+}
+
+func (listener *lazyListener) Accept() (net.Conn, error) {
+	conn, err := listener.inner.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &lazyConn{Conn: conn, listener: listener, config: &listener.config}, nil
+}
+
+func (listener *lazyListener) Close() error {
+	listener.closed.Store(true)
+	return listener.inner.Close()
+}
+
+func (listener *lazyListener) Addr() net.Addr {
+	return listener.inner.Addr()
+}
+
+// A connection whose PROXY header is read on first use
+type lazyConn struct {
+	net.Conn
+	listener *lazyListener
+	config   *Config
+
+	once        sync.Once
+	established net.Conn // valid after once is done, if err is nil
+	err         error
+
+	// Held while recording and applying the read deadline, so that
+	// establish can reapply the latest one after reading the header
+	deadlineMu   sync.Mutex
+	readDeadline time.Time
+}
+
+func (conn *lazyConn) establish() error {
+	conn.once.Do(func() {
+		conn.deadlineMu.Lock()
+		readDeadline := conn.readDeadline
+		conn.deadlineMu.Unlock()
+
+		conn.established, conn.err = conn.config.establish(conn.Conn, readDeadline)
+		if conn.err == nil {
+			// establish restores readDeadline when it's done, which
+			// clobbers any deadline set in the meantime
+			conn.deadlineMu.Lock()
+			if !conn.readDeadline.Equal(readDeadline) {
+				conn.err = conn.Conn.SetReadDeadline(conn.readDeadline)
+			}
+			conn.deadlineMu.Unlock()
+		}
+		if conn.err == errLocalAnswered {
+			conn.Conn.Close()
+			conn.err = io.EOF
+		} else if conn.err != nil {
+			conn.Conn.Close()
+			// Errors caused by closing the connection, or by shutting
+			// down the server, aren't worth reporting
+			if !errors.Is(conn.err, net.ErrClosed) && !conn.listener.closed.Load() {
+				conn.config.logError(conn.Conn.RemoteAddr(), conn.err)
+			}
+		}
+	})
+	return conn.err
+}
+
+func (conn *lazyConn) Read(p []byte) (int, error) {
+	if err := conn.establish(); err != nil {
+		return 0, err
+	}
+	return conn.established.Read(p)
+}
+
+// Return the local address from the PROXY header, or the real local
+// address if the header could not be read.  Blocks until the header has
+// been read, for up to HeaderTimeout.
+func (conn *lazyConn) LocalAddr() net.Addr {
+	if err := conn.establish(); err != nil {
+		return conn.Conn.LocalAddr()
+	}
+	return conn.established.LocalAddr()
+}
+
+// Return the remote address from the PROXY header, or the real remote
+// address if the header could not be read.  Blocks until the header has
+// been read, for up to HeaderTimeout.
+func (conn *lazyConn) RemoteAddr() net.Addr {
+	if err := conn.establish(); err != nil {
+		return conn.Conn.RemoteAddr()
+	}
+	return conn.established.RemoteAddr()
+}
+
+func (conn *lazyConn) SetDeadline(t time.Time) error {
+	conn.deadlineMu.Lock()
+	defer conn.deadlineMu.Unlock()
+	conn.readDeadline = t
+	return conn.Conn.SetDeadline(t)
+}
+
+func (conn *lazyConn) SetReadDeadline(t time.Time) error {
+	conn.deadlineMu.Lock()
+	defer conn.deadlineMu.Unlock()
+	conn.readDeadline = t
+	return conn.Conn.SetReadDeadline(t)
+}
+
+// Return the PROXY protocol header read from the connection, reading
+// it if necessary
+func (conn *lazyConn) ProxyHeader() *Header {
+	if err := conn.establish(); err != nil {
+		return nil
+	}
+	return ConnHeader(conn.established)
+}
+
+// Return the underlying connection.  Its PROXY header may not have been
+// read yet, so reading from it directly can return the header or cause it
+// to be skipped; use it only for operations that don't read, such as
+// [src.agwa.name/go-listener/unix.UnwrapConn].
+func (conn *lazyConn) NetConn() net.Conn {
+	return conn.Conn
+}
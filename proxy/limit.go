@@ -0,0 +1,154 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"container/list"
+	"net"
+	"sync"
+)
+
+// LimitPolicy specifies what a PROXY protocol listener does when a new
+// connection would exceed the limit on pending connections
+type LimitPolicy int
+
+const (
+	// Close the new connection
+	RefuseNew LimitPolicy = iota
+
+	// Close the oldest pending connection to make room for the new one
+	DropOldest
+)
+
+// A connection whose PROXY header has not been read yet, or which has not
+// been returned by Accept yet
+type pendingConn struct {
+	conn       net.Conn
+	ip         string
+	globalElem *list.Element
+	ipElem     *list.Element
+}
+
+// Tracks pending connections and enforces the limits in Config
+type pendingConns struct {
+	mu       sync.Mutex
+	max      int
+	maxPerIP int
+	policy   LimitPolicy
+	all      *list.List // of *pendingConn, oldest first
+	byIP     map[string]*list.List
+}
+
+func newPendingConns(config *Config) *pendingConns {
+	return &pendingConns{
+		max:      config.MaxPending,
+		maxPerIP: config.MaxPendingPerIP,
+		policy:   config.AtLimit,
+		all:      list.New(),
+		byIP:     make(map[string]*list.List),
+	}
+}
+
+func remoteIP(conn net.Conn) string {
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.AddrPort().Addr().Unmap().String()
+	}
+	return ""
+}
+
+// Start tracking conn.  If doing so would exceed a limit, either refuse
+// by returning nil, or close the oldest pending connection, depending on
+// the policy.
+func (p *pendingConns) add(conn net.Conn) *pendingConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc := &pendingConn{conn: conn, ip: remoteIP(conn)}
+	ipList := p.byIP[pc.ip]
+	if p.maxPerIP > 0 && pc.ip != "" && ipList != nil && ipList.Len() >= p.maxPerIP {
+		if p.policy != DropOldest {
+			return nil
+		}
+		p.evict(ipList.Front().Value.(*pendingConn))
+		ipList = p.byIP[pc.ip]
+	}
+	if p.max > 0 && p.all.Len() >= p.max {
+		if p.policy != DropOldest {
+			return nil
+		}
+		p.evict(p.all.Front().Value.(*pendingConn))
+		ipList = p.byIP[pc.ip]
+	}
+
+	pc.globalElem = p.all.PushBack(pc)
+	if pc.ip != "" {
+		if ipList == nil {
+			ipList = list.New()
+			p.byIP[pc.ip] = ipList
+		}
+		pc.ipElem = ipList.PushBack(pc)
+	}
+	return pc
+}
+
+// Stop tracking pc.  It's safe to call this more than once.
+func (p *pendingConns) remove(pc *pendingConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unlink(pc)
+}
+
+// Stop tracking pc and close it, which causes its pending read of the
+// PROXY header to fail.  Must be called with p.mu held.
+func (p *pendingConns) evict(pc *pendingConn) {
+	p.unlink(pc)
+	pc.conn.Close()
+}
+
+func (p *pendingConns) unlink(pc *pendingConn) {
+	if pc.globalElem == nil {
+		return
+	}
+	p.all.Remove(pc.globalElem)
+	pc.globalElem = nil
+	if pc.ipElem != nil {
+		ipList := p.byIP[pc.ip]
+		ipList.Remove(pc.ipElem)
+		if ipList.Len() == 0 {
+			delete(p.byIP, pc.ip)
+		}
+		pc.ipElem = nil
+	}
+}
+
+// Close all pending connections
+func (p *pendingConns) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.all.Len() > 0 {
+		p.evict(p.all.Front().Value.(*pendingConn))
+	}
+}
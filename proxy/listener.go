@@ -23,19 +23,110 @@
 // sale, use or other dealings in this Software without prior written
 // authorization.
 
-// Package proxy implements version 2 of the PROXY protocol (https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt)
+// Package proxy implements versions 1 and 2 of the PROXY protocol (https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt)
 package proxy // import "src.agwa.name/go-listener/proxy"
 
 import (
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
+	"net/netip"
+	"os"
 	"sync"
 	"time"
 )
 
+// Config contains options for a PROXY protocol listener.  The zero value
+// is a valid configuration.
+type Config struct {
+	// The versions of the PROXY protocol to accept.  If zero, only
+	// version 2 is accepted.
+	Versions Versions
+
+	// If either TrustedNetworks or TrustedUIDs is non-empty, then PROXY
+	// headers are only required and honored from connections whose remote
+	// IP address is in TrustedNetworks, or which come from a UNIX domain
+	// socket peer whose user ID is in TrustedUIDs.  Connections from other
+	// sources are handled according to Untrusted.  TrustedUIDs works with
+	// any UNIX domain socket listener, but peer credentials are only
+	// available on Linux, so on other operating systems no UNIX domain
+	// socket peer is trusted.
+	TrustedNetworks []netip.Prefix
+	TrustedUIDs     []int
+	Untrusted       UntrustedPolicy
+
+	// If true, connections which don't begin with a PROXY header are
+	// accepted as if they were not proxied.  Since this requires waiting for
+	// the client to send data, it should only be used with protocols in
+	// which the client speaks first.  If the client doesn't send anything
+	// before the header timeout, the connection is accepted as not proxied.
+	Optional bool
+
+	// How to handle connections whose header uses the LOCAL command, which
+	// load balancers typically use for health checks.  If PassLocal (the
+	// default), they are returned from Accept; use [ConnHeader] and
+	// [Header.Local] to recognize them.  If AnswerLocal, LocalResponse is
+	// written to them and they are closed without being returned from Accept.
+	Local         LocalPolicy
+	LocalResponse []byte
+
+	// How long to wait for a client to send the PROXY header.  If zero,
+	// one minute is used.
+	HeaderTimeout time.Duration
+
+	// The maximum number of pending connections (whose PROXY header has not
+	// been read yet, or which are waiting to be returned by Accept), in
+	// total and from any one IP address.  Zero means no limit.
+	// AtLimit specifies what happens when a new connection would exceed
+	// either limit.
+	MaxPending      int
+	MaxPendingPerIP int
+	AtLimit         LimitPolicy
+
+	// If non-nil, ErrorFunc is called with the client's address when a
+	// connection fails before it can be returned by Accept (e.g. because the
+	// client sent an invalid PROXY header or was refused due to a limit).
+	// ErrorFunc may be called concurrently from multiple goroutines.
+	ErrorFunc func(remoteAddr net.Addr, err error)
+
+	// If non-nil, the errors described above are logged to ErrorLog.
+	ErrorLog *slog.Logger
+
+	// If true, the errors described above are also returned by Accept
+	// as temporary errors, as in earlier versions of this package.  Note
+	// that [net/http.Server] sleeps after Accept returns a temporary error,
+	// delaying all other connections.  Ignored when Lazy is true.
+	AcceptErrors bool
+
+	// If true, Accept returns connections immediately, without reading the
+	// PROXY header first.  Instead, the header is read by the first call to
+	// Read, LocalAddr, or RemoteAddr, and errors are returned from Read.
+	// Note that LocalAddr and RemoteAddr can therefore block for up to
+	// HeaderTimeout, which matters to servers that log or check addresses
+	// before reading.
+	// This avoids a goroutine and channel send per connection, which helps
+	// servers with high connection rates, but means that a slow client
+	// ties up the goroutine that serves it rather than a dedicated one.
+	// MaxPending, MaxPendingPerIP, and AtLimit are ignored in lazy mode.
+	Lazy bool
+}
+
+func (config Config) withDefaults() Config {
+	if config.Versions == 0 {
+		config.Versions = Version2
+	}
+	if config.HeaderTimeout == 0 {
+		config.HeaderTimeout = 1 * time.Minute
+	}
+	return config
+}
+
 type proxyListener struct {
 	inner   net.Listener
+	config  Config
+	pending *pendingConns
 	conns   chan net.Conn
 	errors  chan error
 	done    chan struct{}
@@ -47,12 +138,23 @@ type proxyListener struct {
 // sets the local and remote addresses of the [net.Conn] to the values
 // specified in the PROXY header.
 func NewListener(inner net.Listener) net.Listener {
+	return new(Config).NewListener(inner)
+}
+
+// NewListener creates a [net.Listener] like the package-level [NewListener]
+// function, but using the options in config.
+func (config *Config) NewListener(inner net.Listener) net.Listener {
+	if config.Lazy {
+		return &lazyListener{inner: inner, config: config.withDefaults()}
+	}
 	listener := &proxyListener{
 		inner:  inner,
+		config: config.withDefaults(),
 		conns:  make(chan net.Conn),
 		errors: make(chan error),
 		done:   make(chan struct{}),
 	}
+	listener.pending = newPendingConns(&listener.config)
 	go listener.handleAccepts()
 	return listener
 }
@@ -77,7 +179,18 @@ func (listener *proxyListener) Close() error {
 		return net.ErrClosed
 	default:
 		close(listener.done)
-		return listener.inner.Close()
+		err := listener.inner.Close()
+		listener.pending.closeAll()
+		return err
+	}
+}
+
+func (listener *proxyListener) isClosed() bool {
+	select {
+	case <-listener.done:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -94,40 +207,119 @@ func (listener *proxyListener) handleAccepts() {
 			if !listener.sendError(err) {
 				break
 			}
+		} else if pc := listener.pending.add(conn); pc == nil {
+			conn.Close()
+			if listener.isClosed() {
+				continue
+			}
+			// Don't wait for Accept to return the error, since no
+			// connections are accepted in the meantime
+			err := errors.New("too many pending connections")
+			listener.config.logError(conn.RemoteAddr(), err)
+			if listener.config.AcceptErrors {
+				listener.trySendError(&acceptError{error: err, temporary: true})
+			}
 		} else {
-			go listener.handleConnection(conn)
+			go listener.handleConnection(conn, pc)
 		}
 	}
 }
 
-func (listener *proxyListener) handleConnection(conn net.Conn) {
-	if err := conn.SetReadDeadline(time.Now().Add(1 * time.Minute)); err != nil {
+func (listener *proxyListener) handleConnection(conn net.Conn, pc *pendingConn) {
+	// The connection remains pending until Accept returns it, so that
+	// connections waiting for Accept count towards the limits
+	defer listener.pending.remove(pc)
+
+	proxiedConn, err := listener.config.establish(conn, time.Time{})
+	if err != nil {
 		conn.Close()
-		listener.sendError(&acceptError{error: err, temporary: true})
+		if err != errLocalAnswered {
+			listener.reportError(conn.RemoteAddr(), err)
+		}
 		return
 	}
+	if !listener.sendConn(proxiedConn) {
+		proxiedConn.Close()
+	}
+}
+
+// Read the PROXY header from conn, as dictated by config, and return the
+// connection that should be returned by Accept.  Afterwards, the read
+// deadline of conn is set to readDeadline.
+func (config *Config) establish(conn net.Conn, readDeadline time.Time) (net.Conn, error) {
+	if !config.isTrusted(conn) {
+		if config.Untrusted == AcceptUntrusted {
+			return conn, nil
+		}
+		return nil, fmt.Errorf("connection from untrusted source %s", conn.RemoteAddr())
+	}
 
-	header, err := ReadHeader(conn)
+	headerDeadline := time.Now().Add(config.HeaderTimeout)
+	if !readDeadline.IsZero() && readDeadline.Before(headerDeadline) {
+		headerDeadline = readDeadline
+	}
+	if err := conn.SetReadDeadline(headerDeadline); err != nil {
+		return nil, err
+	}
+
+	var header *Header
+	var err error
+	if config.Optional {
+		var prefix []byte
+		var proxied bool
+		prefix, proxied, err = peekSignature(conn, config.Versions)
+		if !proxied {
+			if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("reading proxy header: %w", err)
+			}
+			if err := conn.SetReadDeadline(readDeadline); err != nil {
+				return nil, err
+			}
+			return &prefixedConn{Conn: conn, prefix: prefix}, nil
+		}
+		header, err = readHeader(&prefixedConn{Conn: conn, prefix: prefix}, config.Versions)
+	} else {
+		header, err = readHeader(conn, config.Versions)
+	}
 	if err != nil {
-		conn.Close()
-		err = fmt.Errorf("reading proxy header: %w", err)
-		listener.sendError(&acceptError{error: err, temporary: true})
-		return
+		return nil, fmt.Errorf("reading proxy header: %w", err)
 	}
 
-	if err := conn.SetReadDeadline(time.Time{}); err != nil {
-		conn.Close()
-		listener.sendError(&acceptError{error: err, temporary: true})
-		return
+	if header.Local && config.Local == AnswerLocal {
+		config.answerLocal(conn)
+		return nil, errLocalAnswered
 	}
 
-	proxyConn := &proxyConn{
-		Conn:       conn,
-		localAddr:  header.LocalAddr,
-		remoteAddr: header.RemoteAddr,
+	if err := conn.SetReadDeadline(readDeadline); err != nil {
+		return nil, err
+	}
+
+	return &proxyConn{
+		Conn:   conn,
+		header: header,
+	}, nil
+}
+
+// Pass an error with an individual connection to ErrorFunc and ErrorLog
+func (config *Config) logError(remoteAddr net.Addr, err error) {
+	if config.ErrorFunc != nil {
+		config.ErrorFunc(remoteAddr, err)
 	}
-	if !listener.sendConn(proxyConn) {
-		proxyConn.Close()
+	if config.ErrorLog != nil {
+		config.ErrorLog.Warn("PROXY protocol connection failed", "remote_addr", remoteAddr.String(), "error", err)
+	}
+}
+
+// Report an error with an individual connection, unless the listener has
+// been closed, in which case the error was probably caused by closing the
+// connection and isn't worth reporting
+func (listener *proxyListener) reportError(remoteAddr net.Addr, err error) {
+	if listener.isClosed() {
+		return
+	}
+	listener.config.logError(remoteAddr, err)
+	if listener.config.AcceptErrors {
+		listener.sendError(&acceptError{error: err, temporary: true})
 	}
 }
 
@@ -140,6 +332,14 @@ func (listener *proxyListener) sendError(err error) bool {
 	}
 }
 
+// Send err to Accept if it's ready to receive it, and otherwise discard it
+func (listener *proxyListener) trySendError(err error) {
+	select {
+	case listener.errors <- err:
+	default:
+	}
+}
+
 func (listener *proxyListener) sendConn(conn net.Conn) bool {
 	select {
 	case listener.conns <- conn:
@@ -0,0 +1,375 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy_test
+
+import (
+	"errors"
+	"bytes"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"src.agwa.name/go-listener/proxy"
+	"src.agwa.name/go-listener/proxy/proxytest"
+)
+
+func listen(t *testing.T, config *proxy.Config) net.Listener {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := config.NewListener(inner)
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+func dialWithHeader(t *testing.T, addr net.Addr) net.Conn {
+	t.Helper()
+	header := &proxy.Header{
+		RemoteAddr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234},
+		LocalAddr:  &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443},
+	}
+	conn, err := new(proxy.Dialer).Dial(addr.Network(), addr.String(), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// Wait for the server to close conn
+func expectClosed(t *testing.T, conn net.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Error("connection was not closed by the server")
+	}
+}
+
+// Check that listeners created by listen honor headers from sources trusted
+// by the trusted config, and reject or accept sources which aren't trusted
+// by the untrusted config according to its Untrusted policy
+func testTrust(t *testing.T, listen func(*testing.T, *proxy.Config) net.Listener, trusted, untrusted proxy.Config) {
+	t.Run("Trusted", func(t *testing.T) {
+		config := trusted
+		listener := listen(t, &config)
+		dialWithHeader(t, listener.Addr())
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if conn.RemoteAddr().String() != "192.0.2.1:1234" {
+			t.Errorf("accepted connection has remote address %s; want 192.0.2.1:1234", conn.RemoteAddr())
+		}
+	})
+
+	t.Run("RejectUntrusted", func(t *testing.T) {
+		errs := make(chan error, 10)
+		config := untrusted
+		config.Untrusted = proxy.RejectUntrusted
+		config.ErrorFunc = func(remoteAddr net.Addr, err error) { errs <- err }
+		listener := listen(t, &config)
+		expectClosed(t, dialWithHeader(t, listener.Addr()))
+		select {
+		case err := <-errs:
+			if !strings.Contains(err.Error(), "untrusted source") {
+				t.Errorf("unexpected error %q", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("ErrorFunc was not called for the untrusted connection")
+		}
+	})
+
+	t.Run("AcceptUntrusted", func(t *testing.T) {
+		config := untrusted
+		config.Untrusted = proxy.AcceptUntrusted
+		listener := listen(t, &config)
+		dialWithHeader(t, listener.Addr())
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if conn.RemoteAddr() != nil && conn.RemoteAddr().String() == "192.0.2.1:1234" {
+			t.Error("header from untrusted source was honored")
+		}
+
+		// The header is delivered to the application as ordinary data
+		signature := []byte("\r\n\r\n\x00\r\nQUIT\n")
+		buf := make([]byte, len(signature))
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, signature) {
+			t.Errorf("read %q from untrusted connection; want the PROXY signature", buf)
+		}
+	})
+}
+
+func TestTrustedNetworks(t *testing.T) {
+	testTrust(t, listen,
+		proxy.Config{TrustedNetworks: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}},
+		proxy.Config{TrustedNetworks: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}},
+	)
+}
+
+func TestPendingLimit(t *testing.T) {
+	errs := make(chan error, 10)
+	listener := listen(t, &proxy.Config{
+		MaxPending: 1,
+		ErrorFunc:  func(remoteAddr net.Addr, err error) { errs <- err },
+	})
+
+	// The first connection sends its header but is not accepted yet, so
+	// it still counts towards the limit
+	first := dialWithHeader(t, listener.Addr())
+	second := dialWithHeader(t, listener.Addr())
+	expectClosed(t, second)
+	select {
+	case err := <-errs:
+		if err.Error() != "too many pending connections" {
+			t.Errorf("unexpected error %q", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("ErrorFunc was not called for the refused connection")
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.RemoteAddr().String() != "192.0.2.1:1234" {
+		t.Errorf("accepted connection has remote address %s; want 192.0.2.1:1234", conn.RemoteAddr())
+	}
+	if _, err := first.Write([]byte("x")); err != nil {
+		t.Errorf("first connection failed: %s", err)
+	}
+}
+
+func TestHeaderTimeout(t *testing.T) {
+	errs := make(chan error, 10)
+	listener := listen(t, &proxy.Config{
+		HeaderTimeout: 100 * time.Millisecond,
+		ErrorFunc:     func(remoteAddr net.Addr, err error) { errs <- err },
+	})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	expectClosed(t, conn)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("ErrorFunc called with %q; want a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("ErrorFunc was not called for the timed out connection")
+	}
+}
+
+func TestAcceptErrorsAtLimit(t *testing.T) {
+	listener := listen(t, &proxy.Config{
+		MaxPending:   1,
+		AcceptErrors: true,
+	})
+
+	// Nobody calls Accept, so the refusals can't be returned from it, but
+	// they must not stop the listener from accepting connections
+	dialWithHeader(t, listener.Addr())
+	for i := 0; i < 3; i++ {
+		expectClosed(t, dialWithHeader(t, listener.Addr()))
+	}
+}
+
+func TestNoErrorsAfterClose(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		errs := make(chan error, 10)
+		listener := listen(t, &proxy.Config{
+			Lazy:      lazy,
+			ErrorFunc: func(remoteAddr net.Addr, err error) { errs <- err },
+		})
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if lazy {
+			// Simulate a server which closes its connections when
+			// shutting down while a Read is waiting for the header
+			accepted, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			readDone := make(chan struct{})
+			go func() {
+				accepted.Read(make([]byte, 1))
+				close(readDone)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			listener.Close()
+			accepted.Close()
+			<-readDone
+		} else {
+			time.Sleep(50 * time.Millisecond)
+			listener.Close()
+			expectClosed(t, conn)
+		}
+
+		select {
+		case err := <-errs:
+			t.Errorf("lazy=%v: ErrorFunc called after Close with %q", lazy, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestLazyDeadlineDuringHeader(t *testing.T) {
+	listener := listen(t, &proxy.Config{Lazy: true})
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		readErr <- err
+	}()
+
+	// Set a deadline while the header is being read, then send the
+	// header; the deadline must still apply to the Read after that
+	time.Sleep(50 * time.Millisecond)
+	conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	header := proxy.Header{
+		RemoteAddr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234},
+		LocalAddr:  &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443},
+	}
+	if _, err := client.Write(header.Format()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-readErr:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("Read returned %v; want a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadline set while reading the header was lost")
+	}
+}
+
+func TestListenerVectors(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		listener := listen(t, &proxy.Config{Versions: proxy.AnyVersion, Lazy: lazy})
+		if err := proxytest.TestListener(listener, proxytest.Vectors()); err != nil {
+			t.Errorf("lazy=%v: %s", lazy, err)
+		}
+	}
+}
+
+// Connect to addr and send a PROXY header with the LOCAL command
+func dialLocal(t *testing.T, addr net.Addr) net.Conn {
+	t.Helper()
+	conn, err := new(proxy.Dialer).Dial(addr.Network(), addr.String(), &proxy.Header{Local: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestAnswerLocal(t *testing.T) {
+	for _, response := range []string{"OK\r\n", ""} {
+		listener := listen(t, &proxy.Config{
+			Local:         proxy.AnswerLocal,
+			LocalResponse: []byte(response),
+		})
+
+		local := dialLocal(t, listener.Addr())
+		local.SetReadDeadline(time.Now().Add(5 * time.Second))
+		got, err := io.ReadAll(local)
+		if err != nil {
+			t.Fatalf("reading response to LOCAL connection: %s", err)
+		}
+		if string(got) != response {
+			t.Errorf("LOCAL connection received %q; want %q", got, response)
+		}
+
+		// The LOCAL connection must not be returned by Accept
+		dialWithHeader(t, listener.Addr())
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header := proxy.ConnHeader(conn); header == nil || header.Local {
+			t.Errorf("Accept returned a LOCAL connection")
+		}
+		conn.Close()
+	}
+}
+
+func TestPassLocal(t *testing.T) {
+	listener := listen(t, &proxy.Config{LocalResponse: []byte("OK\r\n")})
+
+	local := dialLocal(t, listener.Addr())
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if header := proxy.ConnHeader(conn); header == nil || !header.Local {
+		t.Fatalf("Accept returned a connection without a LOCAL header")
+	}
+
+	// LocalResponse is only written with AnswerLocal
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	local.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(local, buf); err != nil || string(buf) != "hello" {
+		t.Errorf("LOCAL connection received %q, %v; want %q", buf, err, "hello")
+	}
+}
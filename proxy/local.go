@@ -0,0 +1,59 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// LocalPolicy specifies how a PROXY protocol listener handles connections
+// whose header uses the LOCAL command
+type LocalPolicy int
+
+const (
+	// Return LOCAL connections from Accept like any other connection
+	PassLocal LocalPolicy = iota
+
+	// Write Config.LocalResponse to LOCAL connections and close them
+	AnswerLocal
+)
+
+// Returned by establish when a LOCAL connection has been answered and
+// should be closed without being reported
+var errLocalAnswered = errors.New("LOCAL connection answered")
+
+// Write the canned response to a LOCAL connection
+func (config *Config) answerLocal(conn net.Conn) {
+	if len(config.LocalResponse) == 0 {
+		return
+	}
+	if err := conn.SetWriteDeadline(time.Now().Add(config.HeaderTimeout)); err != nil {
+		return
+	}
+	conn.Write(config.LocalResponse)
+}
@@ -0,0 +1,238 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// PacketConfig configures a PROXY protocol [net.PacketConn], which reads
+// datagrams that are each prefixed with a version 2 header, as sent by
+// some UDP load balancers
+type PacketConfig struct {
+	// Networks which are trusted to send PROXY headers.  If empty,
+	// all sources are trusted.
+	TrustedNetworks []netip.Prefix
+
+	// What to do with datagrams from untrusted sources.  If AcceptUntrusted,
+	// such datagrams are returned as-is, with their real source address,
+	// and replies to them are sent directly.
+	Untrusted UntrustedPolicy
+
+	// How long to remember the load balancer through which a client's
+	// datagrams arrived, so that replies can be routed back through it.
+	// Each datagram from the client resets the timer.  If zero, defaults
+	// to 2 minutes.
+	AddressTimeout time.Duration
+
+	// The maximum number of clients to remember at once.  Datagrams from
+	// new clients are dropped while the table is full.  If zero, there is
+	// no limit.
+	MaxAddresses int
+
+	// Called for each datagram which is dropped, such as because it lacks a
+	// valid PROXY header.  Must be safe for concurrent use.
+	ErrorFunc func(remoteAddr net.Addr, err error)
+
+	// If non-nil, dropped datagrams are logged to ErrorLog
+	ErrorLog *slog.Logger
+}
+
+// The largest possible UDP payload
+const maxDatagramSize = 65535
+
+var datagramBuffers = sync.Pool{
+	New: func() interface{} { return new([maxDatagramSize]byte) },
+}
+
+type route struct {
+	via      net.Addr // the load balancer's address
+	lastSeen time.Time
+}
+
+type proxyPacketConn struct {
+	net.PacketConn
+	config PacketConfig
+
+	mu        sync.Mutex
+	routes    map[string]*route // keyed by client address
+	lastSweep time.Time
+}
+
+// Wrap inner with a [net.PacketConn] which strips the version 2 PROXY
+// header from each datagram.  ReadFrom returns the client address from
+// the header, and WriteTo sends replies to a client back through the
+// load balancer from which its datagrams arrived.  Replies are sent to the
+// load balancer's address as-is, without a PROXY header, so the load
+// balancer must be able to route them back to the client on its own (e.g.
+// by tracking the flow).  Datagrams without a valid header are dropped.
+func NewPacketConn(inner net.PacketConn) net.PacketConn {
+	return (&PacketConfig{}).NewPacketConn(inner)
+}
+
+// Like [NewPacketConn], but configured by config
+func (config *PacketConfig) NewPacketConn(inner net.PacketConn) net.PacketConn {
+	conn := &proxyPacketConn{
+		PacketConn: inner,
+		config:     *config,
+		routes:     make(map[string]*route),
+		lastSweep:  time.Now(),
+	}
+	if conn.config.AddressTimeout == 0 {
+		conn.config.AddressTimeout = 2 * time.Minute
+	}
+	return conn
+}
+
+func (conn *proxyPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	buf := datagramBuffers.Get().(*[maxDatagramSize]byte)
+	defer datagramBuffers.Put(buf)
+	for {
+		n, via, err := conn.PacketConn.ReadFrom(buf[:])
+		if err != nil {
+			return 0, nil, err
+		}
+		if !conn.config.isTrusted(via) {
+			if conn.config.Untrusted == AcceptUntrusted {
+				return copy(p, buf[:n]), via, nil
+			}
+			conn.config.logError(via, fmt.Errorf("datagram from untrusted source %s", via))
+			continue
+		}
+		header, payload, err := parseDatagram(buf[:n], conn.LocalAddr(), via)
+		if err != nil {
+			conn.config.logError(via, fmt.Errorf("reading proxy header: %w", err))
+			continue
+		}
+		if err := conn.addRoute(header.RemoteAddr, via); err != nil {
+			conn.config.logError(via, err)
+			continue
+		}
+		return copy(p, payload), header.RemoteAddr, nil
+	}
+}
+
+func (conn *proxyPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if via := conn.lookupRoute(addr); via != nil {
+		return conn.PacketConn.WriteTo(p, via)
+	}
+	if conn.config.Untrusted == AcceptUntrusted {
+		return conn.PacketConn.WriteTo(p, addr)
+	}
+	return 0, &net.OpError{Op: "write", Net: conn.LocalAddr().Network(), Source: conn.LocalAddr(), Addr: addr, Err: errors.New("no recent datagrams from address")}
+}
+
+// Record that datagrams from client arrive via the given address
+func (conn *proxyPacketConn) addRoute(client net.Addr, via net.Addr) error {
+	now := time.Now()
+	key := client.String()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if now.Sub(conn.lastSweep) >= conn.config.AddressTimeout {
+		conn.sweep(now)
+	}
+	if r, ok := conn.routes[key]; ok {
+		r.via = via
+		r.lastSeen = now
+		return nil
+	}
+	if conn.config.MaxAddresses > 0 && len(conn.routes) >= conn.config.MaxAddresses {
+		conn.sweep(now)
+		if len(conn.routes) >= conn.config.MaxAddresses {
+			return errors.New("too many clients in address table")
+		}
+	}
+	conn.routes[key] = &route{via: via, lastSeen: now}
+	return nil
+}
+
+// Return the address through which to reach client, or nil if unknown
+func (conn *proxyPacketConn) lookupRoute(client net.Addr) net.Addr {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	r, ok := conn.routes[client.String()]
+	if !ok || time.Since(r.lastSeen) >= conn.config.AddressTimeout {
+		return nil
+	}
+	return r.via
+}
+
+// Remove expired routes; conn.mu must be held
+func (conn *proxyPacketConn) sweep(now time.Time) {
+	for key, r := range conn.routes {
+		if now.Sub(r.lastSeen) >= conn.config.AddressTimeout {
+			delete(conn.routes, key)
+		}
+	}
+	conn.lastSweep = now
+}
+
+// Report whether addr is trusted to send headers
+func (config *PacketConfig) isTrusted(addr net.Addr) bool {
+	if len(config.TrustedNetworks) == 0 {
+		return true
+	}
+	udpAddr, ok := addr.(*net.UDPAddr)
+	return ok && containsAddr(config.TrustedNetworks, udpAddr.AddrPort().Addr().Unmap())
+}
+
+// Pass a dropped datagram's error to ErrorFunc and ErrorLog
+func (config *PacketConfig) logError(remoteAddr net.Addr, err error) {
+	if config.ErrorFunc != nil {
+		config.ErrorFunc(remoteAddr, err)
+	}
+	if config.ErrorLog != nil {
+		config.ErrorLog.Warn("PROXY protocol datagram dropped", "remote_addr", remoteAddr.String(), "error", err)
+	}
+}
+
+// Parse the version 2 header at the beginning of datagram, returning the
+// header and the rest of the datagram.  The header does not alias datagram.
+func parseDatagram(datagram []byte, localAddr, remoteAddr net.Addr) (*Header, []byte, error) {
+	if len(datagram) < 16 || !bytes.HasPrefix(datagram, protocolSignature[:]) {
+		return nil, nil, errors.New("not a proxied datagram")
+	}
+	end := 16 + int(binary.BigEndian.Uint16(datagram[14:16]))
+	if end > len(datagram) {
+		return nil, nil, errors.New("header is longer than datagram")
+	}
+	preamble := datagram[:16]
+	payload := bytes.Clone(datagram[16:end])
+	header, err := parseV2Header(preamble, payload, localAddr, remoteAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, datagram[end:], nil
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"src.agwa.name/go-listener/proxy"
+)
+
+// Return a PROXY packet conn, and a UDP socket from which to send it
+// datagrams as a load balancer
+func listenPacket(t *testing.T, config *proxy.PacketConfig) (net.PacketConn, *net.UDPConn) {
+	t.Helper()
+	inner, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := config.NewPacketConn(inner)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	lb, err := net.DialUDP("udp", nil, inner.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lb.Close() })
+	lb.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn, lb
+}
+
+func TestPacketConn(t *testing.T) {
+	conn, lb := listenPacket(t, new(proxy.PacketConfig))
+
+	client := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	header := proxy.Header{RemoteAddr: client, LocalAddr: &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 53}}
+	if _, err := lb.Write(append(header.Format(), "query"...)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 100)
+	n, addr, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "query" {
+		t.Errorf("ReadFrom returned %q; want \"query\"", buf[:n])
+	}
+	if addr.String() != client.String() {
+		t.Errorf("ReadFrom returned address %s; want %s", addr, client)
+	}
+
+	if _, err := conn.WriteTo([]byte("answer"), addr); err != nil {
+		t.Fatalf("WriteTo failed: %s", err)
+	}
+	n, err = lb.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "answer" {
+		t.Errorf("load balancer received %q; want \"answer\" without a header", buf[:n])
+	}
+}
+
+func TestPacketConnLocal(t *testing.T) {
+	conn, lb := listenPacket(t, new(proxy.PacketConfig))
+
+	header := proxy.Header{Local: true}
+	if _, err := lb.Write(append(header.Format(), "ping"...)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 100)
+	n, addr, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "ping" {
+		t.Errorf("ReadFrom returned %q; want \"ping\"", buf[:n])
+	}
+	if addr.String() != lb.LocalAddr().String() {
+		t.Errorf("ReadFrom returned address %s; want the load balancer's address %s", addr, lb.LocalAddr())
+	}
+}
+
+func TestPacketConnBadSignature(t *testing.T) {
+	errs := make(chan error, 10)
+	conn, lb := listenPacket(t, &proxy.PacketConfig{
+		ErrorFunc: func(remoteAddr net.Addr, err error) { errs <- err },
+	})
+
+	header := proxy.Header{
+		RemoteAddr: &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234},
+		LocalAddr:  &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 53},
+	}
+	bad := append(header.Format(), "bad"...)
+	bad[0] ^= 0xFF
+	if _, err := lb.Write(bad); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lb.Write(append(header.Format(), "good"...)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bad datagram is dropped, so ReadFrom returns the good one
+	buf := make([]byte, 100)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "good" {
+		t.Errorf("ReadFrom returned %q; want \"good\"", buf[:n])
+	}
+	select {
+	case err := <-errs:
+		if err.Error() != "reading proxy header: not a proxied datagram" {
+			t.Errorf("ErrorFunc called with %q", err)
+		}
+	default:
+		t.Error("ErrorFunc was not called for the bad datagram")
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxytest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"time"
+
+	"src.agwa.name/go-listener/proxy"
+)
+
+// How long TestListener waits for the listener to act
+const testTimeout = 5 * time.Second
+
+var testMessage = []byte("proxytest message\n")
+
+// Check that listener, a PROXY protocol listener, handles each of vectors
+// correctly.  Connections with valid headers must be returned from Accept
+// with the addresses in the header, followed by the data sent after the
+// header.  Connections with invalid headers must be closed, either before
+// being returned from Accept or by returning an error from Read.
+//
+// Pass only the vectors which listener is configured to accept; for
+// example, omit version 1 vectors if listener only accepts version 2,
+// omit LOCAL vectors if listener answers LOCAL connections itself, and
+// omit invalid vectors if PROXY headers are optional.  Version 2 vectors
+// are checked with the help of a version 2 connection, so listener must
+// accept version 2 unless lb.Version is 1.
+//
+// TestListener calls listener.Accept, so nothing else may be accepting
+// connections from listener at the same time.
+func TestListener(listener net.Listener, vectors []Vector) error {
+	lb := NewLoadBalancer(listener)
+	lb.Timeout = testTimeout
+	for _, vector := range vectors {
+		var err error
+		if vector.Valid() {
+			err = testValid(listener, lb, vector)
+		} else {
+			err = testInvalid(listener, lb, vector)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", vector.Name, err)
+		}
+	}
+	return nil
+}
+
+func testValid(listener net.Listener, lb *LoadBalancer, vector Vector) error {
+	client, err := lb.DialRaw(append(vector.Data[:len(vector.Data):len(vector.Data)], testMessage...))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	conn, err := accept(listener)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := readMessage(conn, testMessage); err != nil {
+		return err
+	}
+
+	wantRemote, wantLocal := vector.Header.RemoteAddr, vector.Header.LocalAddr
+	if wantRemote == nil {
+		wantRemote, wantLocal = client.LocalAddr(), client.RemoteAddr()
+	}
+	if got := conn.RemoteAddr(); !sameAddr(got, wantRemote) {
+		return fmt.Errorf("remote address is %v; want %v", got, wantRemote)
+	}
+	if got := conn.LocalAddr(); !sameAddr(got, wantLocal) {
+		return fmt.Errorf("local address is %v; want %v", got, wantLocal)
+	}
+	return nil
+}
+
+func testInvalid(listener net.Listener, lb *LoadBalancer, vector Vector) error {
+	client, err := lb.DialRaw(vector.Data)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if closeWriter, ok := client.(interface{ CloseWrite() error }); ok {
+		// Let the listener see the end of truncated headers
+		closeWriter.CloseWrite()
+	}
+
+	// Send a valid connection after the invalid one.  Connections returned
+	// from Accept before it must fail when read.
+	sentinelMessage := []byte("proxytest sentinel\n")
+	sentinel, err := lb.Dial(&proxy.Header{
+		RemoteAddr: net.TCPAddrFromAddrPort(netip.MustParseAddrPort("192.0.2.255:1")),
+		LocalAddr:  net.TCPAddrFromAddrPort(netip.MustParseAddrPort("198.51.100.255:1")),
+	})
+	if err != nil {
+		return err
+	}
+	defer sentinel.Close()
+	if _, err := sentinel.Write(sentinelMessage); err != nil {
+		return err
+	}
+
+	for {
+		conn, err := accept(listener)
+		if err != nil {
+			return err
+		}
+		err = readMessage(conn, sentinelMessage)
+		conn.Close()
+		if err == nil {
+			break
+		} else if !errors.Is(err, errUnexpectedData) && err != io.EOF {
+			// The listener rejected the invalid header lazily
+			continue
+		}
+		return errors.New("connection with invalid header was accepted")
+	}
+
+	// Since the sentinel was accepted, the listener should have finished
+	// with the invalid connection by closing it, unless it is queued for Accept
+	client.SetReadDeadline(time.Now().Add(testTimeout))
+	if _, err := io.Copy(io.Discard, client); isTimeout(err) {
+		return errors.New("connection with invalid header was not closed")
+	}
+	return nil
+}
+
+// Accept a connection from listener, skipping temporary errors
+func accept(listener net.Listener) (net.Conn, error) {
+	deadline := time.Now().Add(testTimeout)
+	for {
+		conn, err := listener.Accept()
+		if err == nil {
+			return conn, nil
+		}
+		var netErr interface{ Temporary() bool }
+		if !errors.As(err, &netErr) || !netErr.Temporary() || time.Now().After(deadline) {
+			return nil, err
+		}
+	}
+}
+
+var errUnexpectedData = errors.New("received unexpected data")
+
+// Read message from conn, returning errUnexpectedData if something else is
+// read, or the error from Read
+func readMessage(conn net.Conn, message []byte) error {
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	buf := make([]byte, len(message))
+	n, err := io.ReadFull(conn, buf)
+	if n > 0 && !bytes.Equal(buf[:n], message[:n]) {
+		return errUnexpectedData
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func sameAddr(a, b net.Addr) bool {
+	return a.Network() == b.Network() && a.String() == b.String()
+}
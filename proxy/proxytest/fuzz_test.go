@@ -0,0 +1,121 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxytest_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"src.agwa.name/go-listener/proxy"
+	"src.agwa.name/go-listener/proxy/proxytest"
+)
+
+// A connection which reads from a byte slice
+type bytesConn struct {
+	reader *bytes.Reader
+}
+
+func newBytesConn(data []byte) *bytesConn {
+	return &bytesConn{reader: bytes.NewReader(data)}
+}
+
+func (conn *bytesConn) Read(p []byte) (int, error)  { return conn.reader.Read(p) }
+func (conn *bytesConn) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+func (conn *bytesConn) Close() error                { return nil }
+func (conn *bytesConn) LocalAddr() net.Addr         { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1} }
+func (conn *bytesConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2}
+}
+func (conn *bytesConn) SetDeadline(t time.Time) error      { return nil }
+func (conn *bytesConn) SetReadDeadline(t time.Time) error  { return nil }
+func (conn *bytesConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func FuzzReadHeader(f *testing.F) {
+	for _, vector := range proxytest.Vectors() {
+		f.Add(vector.Data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		conn := newBytesConn(data)
+		header, err := proxy.ReadHeader(conn)
+		if err != nil {
+			return
+		}
+		consumed := len(data) - conn.reader.Len()
+		if header.Version == 1 && consumed > 107 {
+			t.Fatalf("read %d bytes for a version 1 header", consumed)
+		}
+		if header.Version == 2 && consumed > 16+65535 {
+			t.Fatalf("read %d bytes for a version 2 header", consumed)
+		}
+		header.SSL()
+		header.AzurePrivateLinkID()
+		header.GCPPSCConnectionID()
+		header.AWSVPCEndpointID()
+	})
+}
+
+// Check that headers accepted by ReadHeader survive being formatted and
+// read again
+func FuzzRoundTrip(f *testing.F) {
+	for _, vector := range proxytest.ValidVectors() {
+		f.Add(vector.Data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, err := proxy.ReadHeader(newBytesConn(data))
+		if err != nil {
+			return
+		}
+		var formatted []byte
+		if header.Version == 1 {
+			formatted = header.FormatV1()
+		} else {
+			formatted = header.Format()
+		}
+		conn := newBytesConn(formatted)
+		reparsed, err := proxy.ReadHeader(conn)
+		if err != nil {
+			t.Fatalf("formatted header %q cannot be read: %s", formatted, err)
+		}
+		if conn.reader.Len() != 0 {
+			t.Fatalf("formatted header %q has trailing data", formatted)
+		}
+		if reparsed.Local != header.Local {
+			t.Fatalf("Local changed from %v to %v", header.Local, reparsed.Local)
+		}
+		if got, want := reparsed.RemoteAddr.String(), header.RemoteAddr.String(); got != want {
+			t.Fatalf("remote address changed from %s to %s", want, got)
+		}
+		if got, want := reparsed.LocalAddr.String(), header.LocalAddr.String(); got != want {
+			t.Fatalf("local address changed from %s to %s", want, got)
+		}
+		if len(reparsed.TLVs) != len(header.TLVs) {
+			t.Fatalf("number of TLVs changed from %d to %d", len(header.TLVs), len(reparsed.TLVs))
+		}
+	})
+}
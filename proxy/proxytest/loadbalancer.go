@@ -0,0 +1,99 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxytest
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"src.agwa.name/go-listener/proxy"
+)
+
+// LoadBalancer is a fake load balancer which connects to a PROXY protocol
+// listener and sends chosen headers, for testing servers and listeners
+type LoadBalancer struct {
+	// The network and address of the listener under test
+	Network string
+	Address string
+
+	// The version of the PROXY protocol to send (1 or 2).  If zero,
+	// version 2 is sent.
+	Version int
+
+	// The maximum amount of time to wait for a connection and for the
+	// header to be written.  If zero, there is no timeout.
+	Timeout time.Duration
+}
+
+// Return a LoadBalancer which connects to the address of listener
+func NewLoadBalancer(listener net.Listener) *LoadBalancer {
+	return &LoadBalancer{
+		Network: listener.Addr().Network(),
+		Address: listener.Addr().String(),
+	}
+}
+
+func (lb *LoadBalancer) context() (context.Context, context.CancelFunc) {
+	if lb.Timeout == 0 {
+		return context.Background(), func() {}
+	}
+	return context.WithTimeout(context.Background(), lb.Timeout)
+}
+
+// Connect to the listener and send header, as a load balancer relaying a
+// client connection would
+func (lb *LoadBalancer) Dial(header *proxy.Header) (net.Conn, error) {
+	ctx, cancel := lb.context()
+	defer cancel()
+	dialer := proxy.Dialer{Version: lb.Version}
+	return dialer.DialContext(ctx, lb.Network, lb.Address, header)
+}
+
+// Connect to the listener and send a LOCAL header, as a load balancer
+// performing a health check would
+func (lb *LoadBalancer) HealthCheck() (net.Conn, error) {
+	return lb.Dial(&proxy.Header{Local: true})
+}
+
+// Connect to the listener and send data, which need not be a valid header
+func (lb *LoadBalancer) DialRaw(data []byte) (net.Conn, error) {
+	ctx, cancel := lb.context()
+	defer cancel()
+	conn, err := new(net.Dialer).DialContext(ctx, lb.Network, lb.Address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+	if _, err := conn.Write(data); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,368 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+// Package proxytest provides utilities for testing PROXY protocol handling,
+// including a corpus of valid and invalid headers and a fake load balancer
+// which connects to a listener with chosen headers.
+package proxytest // import "src.agwa.name/go-listener/proxy/proxytest"
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strings"
+
+	"src.agwa.name/go-listener/proxy"
+)
+
+// Vector is a PROXY protocol header test case
+type Vector struct {
+	// A short description of the test case
+	Name string
+
+	// The version of the PROXY protocol which Data claims to use (1 or 2)
+	Version int
+
+	// The header, exactly as sent on the wire
+	Data []byte
+
+	// For valid headers, the header which should be parsed from Data.  If
+	// its RemoteAddr and LocalAddr are nil, the real addresses of the
+	// connection should be used, as for LOCAL and UNKNOWN headers.  Nil
+	// for invalid headers.
+	Header *proxy.Header
+}
+
+// Report whether v is a valid header
+func (v Vector) Valid() bool {
+	return v.Header != nil
+}
+
+var v2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// Construct a version 2 header with the given version/command byte,
+// family, and payload, with a correct length field
+func v2(versionCommand byte, family byte, payload ...[]byte) []byte {
+	var length int
+	for _, p := range payload {
+		length += len(p)
+	}
+	data := append([]byte(nil), v2Signature...)
+	data = append(data, versionCommand, family)
+	data = binary.BigEndian.AppendUint16(data, uint16(length))
+	for _, p := range payload {
+		data = append(data, p...)
+	}
+	return data
+}
+
+func tlv(tlvType byte, value []byte) []byte {
+	data := []byte{tlvType}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(value)))
+	return append(data, value...)
+}
+
+func ipPayload(remote, local netip.AddrPort) []byte {
+	var data []byte
+	data = append(data, remote.Addr().AsSlice()...)
+	data = append(data, local.Addr().AsSlice()...)
+	data = binary.BigEndian.AppendUint16(data, remote.Port())
+	data = binary.BigEndian.AppendUint16(data, local.Port())
+	return data
+}
+
+func unixPayload(remote, local string) []byte {
+	data := make([]byte, 216)
+	copy(data[0:108], remote)
+	copy(data[108:216], local)
+	return data
+}
+
+var (
+	remote4 = netip.MustParseAddrPort("192.0.2.1:56324")
+	local4  = netip.MustParseAddrPort("198.51.100.1:443")
+	remote6 = netip.MustParseAddrPort("[2001:db8::1]:56324")
+	local6  = netip.MustParseAddrPort("[2001:db8:ffff::1]:443")
+)
+
+func tcpHeader(remote, local netip.AddrPort, tlvs ...proxy.TLV) *proxy.Header {
+	return &proxy.Header{
+		RemoteAddr: net.TCPAddrFromAddrPort(remote),
+		LocalAddr:  net.TCPAddrFromAddrPort(local),
+		TLVs:       tlvs,
+	}
+}
+
+func udpHeader(remote, local netip.AddrPort) *proxy.Header {
+	return &proxy.Header{
+		RemoteAddr: net.UDPAddrFromAddrPort(remote),
+		LocalAddr:  net.UDPAddrFromAddrPort(local),
+	}
+}
+
+func unixHeader(network, remote, local string) *proxy.Header {
+	return &proxy.Header{
+		RemoteAddr: &net.UnixAddr{Net: network, Name: remote},
+		LocalAddr:  &net.UnixAddr{Net: network, Name: local},
+	}
+}
+
+// Return a header with the checksum TLV, computed by [proxy.Header.Format]
+func withChecksum(header *proxy.Header) []byte {
+	header.TLVs = append(header.TLVs, proxy.TLV{Type: proxy.TypeCRC32C})
+	return header.Format()
+}
+
+// Return the conformance test vectors.  Each call returns a new slice,
+// which the caller may modify.
+func Vectors() []Vector {
+	vectors := append(validVectors(), invalidVectors()...)
+	for i := range vectors {
+		if vectors[i].Header != nil {
+			vectors[i].Header.Version = vectors[i].Version
+		}
+	}
+	return vectors
+}
+
+// Return the valid test vectors, for which Header is non-nil
+func ValidVectors() []Vector {
+	return filterVectors(Vectors(), true)
+}
+
+// Return the invalid test vectors, for which Header is nil
+func InvalidVectors() []Vector {
+	return filterVectors(Vectors(), false)
+}
+
+func filterVectors(vectors []Vector, valid bool) []Vector {
+	var filtered []Vector
+	for _, v := range vectors {
+		if v.Valid() == valid {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+func validVectors() []Vector {
+	ssl := &proxy.SSLInfo{
+		Client: proxy.ClientSSL | proxy.ClientCertConn,
+		TLVs: []proxy.TLV{
+			{Type: proxy.TypeSSLVersion, Value: []byte("TLSv1.3")},
+			{Type: proxy.TypeSSLCN, Value: []byte("client.example")},
+		},
+	}
+	checksummed := tcpHeader(remote4, local4)
+	return []Vector{
+		{
+			Name:    "v1 TCP4",
+			Version: 1,
+			Data:    []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"),
+			Header:  tcpHeader(remote4, local4),
+		},
+		{
+			Name:    "v1 TCP6",
+			Version: 1,
+			Data:    []byte("PROXY TCP6 2001:db8::1 2001:db8:ffff::1 56324 443\r\n"),
+			Header:  tcpHeader(remote6, local6),
+		},
+		{
+			Name:    "v1 TCP6 maximum length",
+			Version: 1,
+			Data:    []byte("PROXY TCP6 ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff 65535 65535\r\n"),
+			Header: tcpHeader(
+				netip.MustParseAddrPort("[ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff]:65535"),
+				netip.MustParseAddrPort("[ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff]:65535"),
+			),
+		},
+		{
+			Name:    "v1 UNKNOWN",
+			Version: 1,
+			Data:    []byte("PROXY UNKNOWN\r\n"),
+			Header:  &proxy.Header{},
+		},
+		{
+			Name:    "v1 UNKNOWN with addresses",
+			Version: 1,
+			Data:    []byte("PROXY UNKNOWN 192.0.2.1 198.51.100.1 56324 443\r\n"),
+			Header:  &proxy.Header{},
+		},
+		{
+			Name:    "v2 TCP over IPv4",
+			Version: 2,
+			Data:    v2(0x21, 0x11, ipPayload(remote4, local4)),
+			Header:  tcpHeader(remote4, local4),
+		},
+		{
+			Name:    "v2 TCP over IPv6",
+			Version: 2,
+			Data:    v2(0x21, 0x21, ipPayload(remote6, local6)),
+			Header:  tcpHeader(remote6, local6),
+		},
+		{
+			Name:    "v2 UDP over IPv4",
+			Version: 2,
+			Data:    v2(0x21, 0x12, ipPayload(remote4, local4)),
+			Header:  udpHeader(remote4, local4),
+		},
+		{
+			Name:    "v2 UDP over IPv6",
+			Version: 2,
+			Data:    v2(0x21, 0x22, ipPayload(remote6, local6)),
+			Header:  udpHeader(remote6, local6),
+		},
+		{
+			Name:    "v2 UNIX stream",
+			Version: 2,
+			Data:    v2(0x21, 0x31, unixPayload("/run/client.sock", "/run/server.sock")),
+			Header:  unixHeader("unix", "/run/client.sock", "/run/server.sock"),
+		},
+		{
+			Name:    "v2 UNIX datagram",
+			Version: 2,
+			Data:    v2(0x21, 0x32, unixPayload("/run/client.sock", "/run/server.sock")),
+			Header:  unixHeader("unixgram", "/run/client.sock", "/run/server.sock"),
+		},
+		{
+			Name:    "v2 UNIX abstract",
+			Version: 2,
+			Data:    v2(0x21, 0x31, unixPayload("\x00client", "\x00server")),
+			Header:  unixHeader("unix", "@client", "@server"),
+		},
+		{
+			Name:    "v2 UNIX maximum length path",
+			Version: 2,
+			Data:    v2(0x21, 0x31, unixPayload(strings.Repeat("c", 108), strings.Repeat("s", 108))),
+			Header:  unixHeader("unix", strings.Repeat("c", 108), strings.Repeat("s", 108)),
+		},
+		{
+			Name:    "v2 LOCAL",
+			Version: 2,
+			Data:    v2(0x20, 0x00),
+			Header:  &proxy.Header{Local: true},
+		},
+		{
+			Name:    "v2 LOCAL with TCP over IPv4 addresses",
+			Version: 2,
+			Data:    v2(0x20, 0x11, ipPayload(remote4, local4)),
+			Header:  &proxy.Header{Local: true},
+		},
+		{
+			Name:    "v2 LOCAL with TLVs",
+			Version: 2,
+			Data:    v2(0x20, 0x00, tlv(proxy.TypeNoop, nil)),
+			Header:  &proxy.Header{Local: true, TLVs: []proxy.TLV{{Type: proxy.TypeNoop, Value: []byte{}}}},
+		},
+		{
+			Name:    "v2 with ALPN and authority TLVs",
+			Version: 2,
+			Data:    v2(0x21, 0x11, ipPayload(remote4, local4), tlv(proxy.TypeALPN, []byte("h2")), tlv(proxy.TypeAuthority, []byte("www.example.com"))),
+			Header: tcpHeader(remote4, local4,
+				proxy.TLV{Type: proxy.TypeALPN, Value: []byte("h2")},
+				proxy.TLV{Type: proxy.TypeAuthority, Value: []byte("www.example.com")},
+			),
+		},
+		{
+			Name:    "v2 with empty TLV",
+			Version: 2,
+			Data:    v2(0x21, 0x11, ipPayload(remote4, local4), tlv(proxy.TypeNoop, nil)),
+			Header:  tcpHeader(remote4, local4, proxy.TLV{Type: proxy.TypeNoop, Value: []byte{}}),
+		},
+		{
+			Name:    "v2 with maximum length TLV",
+			Version: 2,
+			Data:    v2(0x21, 0x11, ipPayload(remote4, local4), tlv(proxy.TypeNoop, make([]byte, 65535-12-3))),
+			Header:  tcpHeader(remote4, local4, proxy.TLV{Type: proxy.TypeNoop, Value: make([]byte, 65535-12-3)}),
+		},
+		{
+			Name:    "v2 with duplicate TLVs",
+			Version: 2,
+			Data:    v2(0x21, 0x11, ipPayload(remote4, local4), tlv(proxy.TypeALPN, []byte("h2")), tlv(proxy.TypeALPN, []byte("http/1.1"))),
+			Header: tcpHeader(remote4, local4,
+				proxy.TLV{Type: proxy.TypeALPN, Value: []byte("h2")},
+				proxy.TLV{Type: proxy.TypeALPN, Value: []byte("http/1.1")},
+			),
+		},
+		{
+			Name:    "v2 with unknown TLV",
+			Version: 2,
+			Data:    v2(0x21, 0x11, ipPayload(remote4, local4), tlv(0xF0, []byte{1, 2, 3})),
+			Header:  tcpHeader(remote4, local4, proxy.TLV{Type: 0xF0, Value: []byte{1, 2, 3}}),
+		},
+		{
+			Name:    "v2 with SSL TLV",
+			Version: 2,
+			Data:    v2(0x21, 0x11, ipPayload(remote4, local4), tlv(proxy.TypeSSL, ssl.TLV().Value)),
+			Header:  tcpHeader(remote4, local4, ssl.TLV()),
+		},
+		{
+			Name:    "v2 with correct CRC32C",
+			Version: 2,
+			Data:    withChecksum(checksummed),
+			Header:  checksummed,
+		},
+	}
+}
+
+func invalidVectors() []Vector {
+	badChecksum := withChecksum(tcpHeader(remote4, local4))
+	badChecksum[len(badChecksum)-1] ^= 0xFF
+
+	return []Vector{
+		{Name: "empty", Version: 2, Data: []byte{}},
+		{Name: "not a header", Version: 2, Data: []byte("GET / HTTP/1.1\r\n\r\n")},
+		{Name: "v1 without CRLF", Version: 1, Data: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443")},
+		{Name: "v1 with LF only", Version: 1, Data: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\n")},
+		{Name: "v1 too long", Version: 1, Data: []byte("PROXY UNKNOWN " + strings.Repeat("x", 100) + "\r\n")},
+		{Name: "v1 missing fields", Version: 1, Data: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324\r\n")},
+		{Name: "v1 extra field", Version: 1, Data: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443 1\r\n")},
+		{Name: "v1 double space", Version: 1, Data: []byte("PROXY TCP4  192.0.2.1 198.51.100.1 56324 443\r\n")},
+		{Name: "v1 lowercase protocol", Version: 1, Data: []byte("PROXY tcp4 192.0.2.1 198.51.100.1 56324 443\r\n")},
+		{Name: "v1 unsupported protocol", Version: 1, Data: []byte("PROXY UDP4 192.0.2.1 198.51.100.1 56324 443\r\n")},
+		{Name: "v1 invalid IP address", Version: 1, Data: []byte("PROXY TCP4 192.0.2.256 198.51.100.1 56324 443\r\n")},
+		{Name: "v1 TCP4 with IPv6 address", Version: 1, Data: []byte("PROXY TCP4 2001:db8::1 198.51.100.1 56324 443\r\n")},
+		{Name: "v1 TCP6 with IPv4 address", Version: 1, Data: []byte("PROXY TCP6 192.0.2.1 2001:db8:ffff::1 56324 443\r\n")},
+		{Name: "v1 port out of range", Version: 1, Data: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 65536 443\r\n")},
+		{Name: "v1 port with leading zero", Version: 1, Data: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 056324 443\r\n")},
+		{Name: "v1 negative port", Version: 1, Data: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 -1 443\r\n")},
+		{Name: "v2 truncated signature", Version: 2, Data: v2Signature[:10]},
+		{Name: "v2 truncated preamble", Version: 2, Data: v2(0x21, 0x11, ipPayload(remote4, local4))[:14]},
+		{Name: "v2 truncated addresses", Version: 2, Data: v2(0x21, 0x11, ipPayload(remote4, local4))[:22]},
+		{Name: "v2 length exceeds data", Version: 2, Data: append(v2(0x21, 0x11)[:14], 0xFF, 0xFF)},
+		{Name: "v2 bad signature", Version: 2, Data: append([]byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0B}, v2(0x21, 0x11, ipPayload(remote4, local4))[12:]...)},
+		{Name: "v2 version 1", Version: 2, Data: v2(0x11, 0x11, ipPayload(remote4, local4))},
+		{Name: "v2 version 3", Version: 2, Data: v2(0x31, 0x11, ipPayload(remote4, local4))},
+		{Name: "v2 unknown command", Version: 2, Data: v2(0x22, 0x11, ipPayload(remote4, local4))},
+		{Name: "v2 unknown family", Version: 2, Data: v2(0x21, 0x41, ipPayload(remote4, local4))},
+		{Name: "v2 TCP over IPv4 too short", Version: 2, Data: v2(0x21, 0x11, ipPayload(remote4, local4)[:11])},
+		{Name: "v2 TCP over IPv6 too short", Version: 2, Data: v2(0x21, 0x21, ipPayload(remote4, local4))},
+		{Name: "v2 UNIX too short", Version: 2, Data: v2(0x21, 0x31, unixPayload("/a", "/b")[:215])},
+		{Name: "v2 truncated TLV", Version: 2, Data: v2(0x21, 0x11, ipPayload(remote4, local4), []byte{proxy.TypeNoop, 0})},
+		{Name: "v2 TLV length exceeds header", Version: 2, Data: v2(0x21, 0x11, ipPayload(remote4, local4), []byte{proxy.TypeALPN, 0, 3, 'h', '2'})},
+		{Name: "v2 incorrect CRC32C", Version: 2, Data: badChecksum},
+		{Name: "v2 short CRC32C", Version: 2, Data: v2(0x21, 0x11, ipPayload(remote4, local4), tlv(proxy.TypeCRC32C, []byte{0, 0}))},
+	}
+}
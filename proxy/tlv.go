@@ -0,0 +1,259 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Types of TLVs (type-length-value fields) which can appear in a version 2 header
+const (
+	TypeALPN      = 0x01
+	TypeAuthority = 0x02
+	TypeCRC32C    = 0x03
+	TypeNoop      = 0x04
+	TypeUniqueID  = 0x05
+	TypeSSL       = 0x20
+	TypeNetNS     = 0x30
+
+	// Sub-types of TLVs which can appear within a TypeSSL TLV
+	TypeSSLVersion = 0x21
+	TypeSSLCN      = 0x22
+	TypeSSLCipher  = 0x23
+	TypeSSLSigAlg  = 0x24
+	TypeSSLKeyAlg  = 0x25
+
+	// Vendor-specific types
+	TypeGCP   = 0xE0 // Google Cloud Private Service Connect
+	TypeAWS   = 0xEA // AWS VPC endpoints
+	TypeAzure = 0xEE // Azure Private Link
+)
+
+// Bits of the client field of a TypeSSL TLV
+const (
+	ClientSSL      = 0x01
+	ClientCertConn = 0x02
+	ClientCertSess = 0x04
+)
+
+const (
+	awsSubtypeVPCEndpointID   = 0x01
+	azureSubtypePrivateLinkID = 0x01
+)
+
+// A TLV (type-length-value field) from a version 2 PROXY header
+type TLV struct {
+	Type  byte
+	Value []byte
+}
+
+func parseTLVs(data []byte) ([]TLV, error) {
+	var tlvs []TLV
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, errors.New("truncated TLV")
+		}
+		length := int(binary.BigEndian.Uint16(data[1:3]))
+		if len(data) < 3+length {
+			return nil, errors.New("TLV length exceeds header length")
+		}
+		tlvs = append(tlvs, TLV{Type: data[0], Value: data[3 : 3+length]})
+		data = data[3+length:]
+	}
+	return tlvs, nil
+}
+
+func appendTLV(buf []byte, tlvType byte, value []byte) []byte {
+	if len(value) > maxPayloadLength {
+		panic("proxy: TLV value is longer than 65535 bytes")
+	}
+	buf = append(buf, tlvType)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(value)))
+	return append(buf, value...)
+}
+
+func findTLV(tlvs []TLV, tlvType byte) ([]byte, bool) {
+	for _, tlv := range tlvs {
+		if tlv.Type == tlvType {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SSLInfo contains information about the TLS connection between the
+// client and the proxy, from a TypeSSL TLV
+type SSLInfo struct {
+	// Bit field containing ClientSSL, ClientCertConn, and ClientCertSess
+	Client byte
+
+	// Zero if the client presented a certificate which was successfully verified
+	Verify uint32
+
+	// Sub-TLVs, such as TypeSSLVersion and TypeSSLCN
+	TLVs []TLV
+}
+
+func parseSSLInfo(value []byte) (*SSLInfo, error) {
+	if len(value) < 5 {
+		return nil, errors.New("SSL TLV is too short")
+	}
+	tlvs, err := parseTLVs(value[5:])
+	if err != nil {
+		return nil, err
+	}
+	return &SSLInfo{
+		Client: value[0],
+		Verify: binary.BigEndian.Uint32(value[1:5]),
+		TLVs:   tlvs,
+	}, nil
+}
+
+// Return a TypeSSL TLV containing info, for use in [Header.TLVs].  TLV
+// panics if any of info's sub-TLVs is longer than 65535 bytes.
+func (info *SSLInfo) TLV() TLV {
+	value := []byte{info.Client}
+	value = binary.BigEndian.AppendUint32(value, info.Verify)
+	for _, tlv := range info.TLVs {
+		value = appendTLV(value, tlv.Type, tlv.Value)
+	}
+	return TLV{Type: TypeSSL, Value: value}
+}
+
+// Report whether the client connected to the proxy over SSL/TLS
+func (info *SSLInfo) IsSSL() bool {
+	return info.Client&ClientSSL != 0
+}
+
+// Report whether the client presented a certificate which was verified
+func (info *SSLInfo) CertVerified() bool {
+	return info.Client&(ClientCertConn|ClientCertSess) != 0 && info.Verify == 0
+}
+
+func (info *SSLInfo) stringTLV(tlvType byte) string {
+	value, _ := findTLV(info.TLVs, tlvType)
+	return string(value)
+}
+
+// Return the SSL/TLS version (e.g. "TLSv1.3"), or "" if not specified
+func (info *SSLInfo) Version() string { return info.stringTLV(TypeSSLVersion) }
+
+// Return the Common Name of the client certificate's subject, or "" if not specified
+func (info *SSLInfo) CN() string { return info.stringTLV(TypeSSLCN) }
+
+// Return the name of the cipher (e.g. "ECDHE-RSA-AES128-GCM-SHA256"), or "" if not specified
+func (info *SSLInfo) Cipher() string { return info.stringTLV(TypeSSLCipher) }
+
+// Return the name of the algorithm used to sign the server certificate, or "" if not specified
+func (info *SSLInfo) SigAlg() string { return info.stringTLV(TypeSSLSigAlg) }
+
+// Return the name of the algorithm used to generate the server key, or "" if not specified
+func (info *SSLInfo) KeyAlg() string { return info.stringTLV(TypeSSLKeyAlg) }
+
+// Return the value of the first TLV with the given type, and whether
+// such a TLV was found.  Use this to access TLV types which don't have
+// a dedicated accessor.
+func (header *Header) TLV(tlvType byte) ([]byte, bool) {
+	return findTLV(header.TLVs, tlvType)
+}
+
+// Return the application protocol negotiated by the client with the
+// proxy using ALPN (e.g. "h2"), or "" if not specified
+func (header *Header) ALPN() string {
+	value, _ := header.TLV(TypeALPN)
+	return string(value)
+}
+
+// Return the host name sent by the client to the proxy (typically from
+// SNI), or "" if not specified
+func (header *Header) Authority() string {
+	value, _ := header.TLV(TypeAuthority)
+	return string(value)
+}
+
+// Return the proxy-assigned unique ID of the connection, or nil if not specified
+func (header *Header) UniqueID() []byte {
+	value, _ := header.TLV(TypeUniqueID)
+	return value
+}
+
+// Return information about the client's SSL/TLS connection to the proxy,
+// or nil if not specified or malformed
+func (header *Header) SSL() *SSLInfo {
+	value, ok := header.TLV(TypeSSL)
+	if !ok {
+		return nil
+	}
+	info, err := parseSSLInfo(value)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// Return the name of the network namespace in which the proxy accepted
+// the connection, or "" if not specified
+func (header *Header) NetNS() string {
+	value, _ := header.TLV(TypeNetNS)
+	return string(value)
+}
+
+func (header *Header) vendorTLV(tlvType byte, subtype byte) ([]byte, bool) {
+	for _, tlv := range header.TLVs {
+		if tlv.Type == tlvType && len(tlv.Value) > 0 && tlv.Value[0] == subtype {
+			return tlv.Value[1:], true
+		}
+	}
+	return nil, false
+}
+
+// Return the ID of the AWS VPC endpoint through which the client
+// connected, or "" if not specified
+func (header *Header) AWSVPCEndpointID() string {
+	value, _ := header.vendorTLV(TypeAWS, awsSubtypeVPCEndpointID)
+	return string(value)
+}
+
+// Return the LinkID of the Azure Private Endpoint through which the client
+// connected, and whether it was specified
+func (header *Header) AzurePrivateLinkID() (uint32, bool) {
+	value, ok := header.vendorTLV(TypeAzure, azureSubtypePrivateLinkID)
+	if !ok || len(value) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(value), true
+}
+
+// Return the ID of the Google Cloud Private Service Connect endpoint
+// through which the client connected, and whether it was specified
+func (header *Header) GCPPSCConnectionID() (uint64, bool) {
+	value, ok := header.TLV(TypeGCP)
+	if !ok || len(value) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(value), true
+}
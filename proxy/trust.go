@@ -0,0 +1,116 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+
+	"src.agwa.name/go-listener/unix"
+)
+
+// UntrustedPolicy specifies how a PROXY protocol listener handles
+// connections from sources which are not trusted to send PROXY headers
+type UntrustedPolicy int
+
+const (
+	// Close connections from untrusted sources
+	RejectUntrusted UntrustedPolicy = iota
+
+	// Accept connections from untrusted sources as if they were not proxied.
+	// The connection's addresses are the real addresses, and nothing is
+	// read from the connection, so any PROXY header sent by the client is
+	// delivered to the application as ordinary data.
+	AcceptUntrusted
+)
+
+// Report whether config restricts which sources are trusted to send headers
+func (config *Config) restrictsSources() bool {
+	return len(config.TrustedNetworks) > 0 || len(config.TrustedUIDs) > 0
+}
+
+// Report whether conn comes from a source which is trusted to send headers
+func (config *Config) isTrusted(conn net.Conn) bool {
+	if !config.restrictsSources() {
+		return true
+	}
+	if unixConn := unix.UnwrapConn(conn); unixConn != nil {
+		cred, err := unixConn.PeerCredentials()
+		return err == nil && slices.Contains(config.TrustedUIDs, cred.UID)
+	}
+	tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	return containsAddr(config.TrustedNetworks, tcpAddr.AddrPort().Addr().Unmap())
+}
+
+// Report whether addr is contained in any of prefixes
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse a network in CIDR notation, or a single IP address, for use in
+// [Config.TrustedNetworks]
+func ParseNetwork(str string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(str); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(str)
+	if err != nil {
+		return netip.Prefix{}, errors.New("invalid network " + str + ": must be an IP address or CIDR prefix")
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Read from conn until it's possible to tell whether it begins with the
+// signature of one of the given PROXY protocol versions.  Return the bytes
+// read and whether a signature was found.
+func peekSignature(conn net.Conn, versions Versions) ([]byte, bool, error) {
+	buf := make([]byte, 0, len(protocolSignature))
+	for {
+		v1 := versions&Version1 != 0 && bytes.HasPrefix(v1Signature, buf[:min(len(buf), len(v1Signature))])
+		v2 := versions&Version2 != 0 && bytes.HasPrefix(protocolSignature[:], buf)
+		if v1 && len(buf) >= len(v1Signature) || v2 && len(buf) == len(protocolSignature) {
+			return buf, true, nil
+		} else if !v1 && !v2 {
+			return buf, false, nil
+		}
+		n, err := conn.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			return buf, false, err
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package proxy_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"src.agwa.name/go-listener/proxy"
+	"src.agwa.name/go-listener/unix"
+)
+
+func TestTrustedUIDs(t *testing.T) {
+	trusted := proxy.Config{TrustedUIDs: []int{os.Getuid()}}
+	untrusted := proxy.Config{TrustedUIDs: []int{os.Getuid() + 1}}
+
+	// A listener created by the unix package
+	t.Run("Unix", func(t *testing.T) {
+		testTrust(t, func(t *testing.T, config *proxy.Config) net.Listener {
+			inner, err := unix.Listen(filepath.Join(t.TempDir(), "socket"), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			listener := config.NewListener(inner)
+			t.Cleanup(func() { listener.Close() })
+			return listener
+		}, trusted, untrusted)
+	})
+
+	// A listener created elsewhere, which accepts plain *net.UnixConns
+	t.Run("Net", func(t *testing.T) {
+		testTrust(t, func(t *testing.T, config *proxy.Config) net.Listener {
+			inner, err := net.Listen("unix", filepath.Join(t.TempDir(), "socket"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			listener := config.NewListener(inner)
+			t.Cleanup(func() { listener.Close() })
+			return listener
+		}, trusted, untrusted)
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"src.agwa.name/go-listener/cert"
+)
+
+// Client certificate authentication options for a TLS listener
+type clientAuthParams struct {
+	authType tls.ClientAuthType
+	getCAs   cert.GetCertPoolFunc        // nil if client_ca not specified
+	getCRLs  cert.GetRevocationListsFunc // nil if crl not specified
+}
+
+func parseClientAuthParams(params map[string]interface{}) (*clientAuthParams, error) {
+	p := new(clientAuthParams)
+
+	if path, ok := params["client_ca"].(string); ok {
+		if strings.HasSuffix(path, "/") {
+			p.getCAs = cert.GetCertPoolFromDirectory(path)
+		} else {
+			p.getCAs = cert.GetCertPoolFromFile(path)
+		}
+		if _, err := p.getCAs(); err != nil {
+			return nil, fmt.Errorf("TLS listener has invalid client_ca %s: %w", path, err)
+		}
+		p.authType = tls.RequireAndVerifyClientCert
+	}
+
+	switch clientAuth, _ := params["client_auth"].(string); clientAuth {
+	case "":
+	case "none":
+		p.authType = tls.NoClientCert
+	case "request":
+		p.authType = tls.RequestClientCert
+	case "require":
+		p.authType = tls.RequireAndVerifyClientCert
+	case "verify-if-given":
+		p.authType = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("TLS listener has invalid client_auth %q; must be none, request, require, or verify-if-given", clientAuth)
+	}
+	if p.getCAs == nil && (p.authType == tls.RequireAndVerifyClientCert || p.authType == tls.VerifyClientCertIfGiven) {
+		return nil, errors.New("TLS listener requires client_ca to verify client certificates")
+	}
+
+	if path, ok := params["crl"].(string); ok {
+		if p.authType != tls.RequireAndVerifyClientCert && p.authType != tls.VerifyClientCertIfGiven {
+			return nil, errors.New("TLS listener has crl but does not verify client certificates")
+		}
+		p.getCRLs = cert.GetRevocationListsFromFile(path)
+		if _, err := p.getCRLs(); err != nil {
+			return nil, fmt.Errorf("TLS listener has invalid crl %s: %w", path, err)
+		}
+	}
+
+	return p, nil
+}
+
+// Return a VerifyConnection function which rejects client certificates
+// that have been revoked by one of the CRLs returned by getCRLs.  The
+// connection is accepted if at least one verified chain contains no
+// revoked certificates.  As with OpenSSL, a chain is also rejected if the
+// CRL for one of its issuers is past its NextUpdate time, since the CRL
+// may not list certificates which were revoked after it expired.
+func checkRevocation(getCRLs cert.GetRevocationListsFunc) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.VerifiedChains) == 0 {
+			return nil
+		}
+		crls, err := getCRLs()
+		if err != nil {
+			return fmt.Errorf("loading CRLs: %w", err)
+		}
+		now := time.Now()
+		for _, chain := range state.VerifiedChains {
+			if err = checkChain(chain, crls, now); err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+}
+
+// Return an error if any certificate in chain has been revoked, or its
+// issuer's CRL has expired
+func checkChain(chain []*x509.Certificate, crls []*x509.RevocationList, now time.Time) error {
+	for i := 0; i+1 < len(chain); i++ {
+		if err := checkRevoked(chain[i].SerialNumber, chain[i+1], crls, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return an error if the certificate with the given serial number, issued
+// by issuer, is revoked by any of crls which were signed by issuer, or if
+// any of those crls has expired
+func checkRevoked(serial *big.Int, issuer *x509.Certificate, crls []*x509.RevocationList, now time.Time) error {
+	for _, crl := range crls {
+		if string(crl.RawIssuer) != string(issuer.RawSubject) || crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+			return fmt.Errorf("CRL for %s expired at %s", issuer.Subject, crl.NextUpdate.Format(time.RFC3339))
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(serial) == 0 {
+				return errors.New("client certificate has been revoked")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"src.agwa.name/go-listener"
+)
+
+// Write a CRL issued by ca, which revokes the given serial numbers and
+// expires at nextUpdate, to path
+func writeCRL(t *testing.T, path string, ca *testCA, nextUpdate time.Time, revoked ...int64) {
+	t.Helper()
+	template := &x509.RevocationList{
+		Number:     big.NewInt(time.Now().UnixNano()),
+		ThisUpdate: time.Now().Add(-2 * time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	for _, serial := range revoked {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Hour),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFileChanged(t, path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+// Check that a client presenting clientCert, if specified, is accepted
+func expectAccepted(t *testing.T, server *testServer, addr string, description string, clientCert ...tls.Certificate) {
+	t.Helper()
+	if _, result, err := dialTLS(addr, server.clientConfig(clientCert...)); err != nil || result != "tls " {
+		t.Errorf("%s: got %q, %v; want connection to be accepted", description, result, err)
+	}
+}
+
+// Check that a client presenting clientCert, if specified, is rejected
+func expectRejected(t *testing.T, server *testServer, addr string, description string, clientCert ...tls.Certificate) {
+	t.Helper()
+	if _, result, err := dialTLS(addr, server.clientConfig(clientCert...)); err == nil {
+		t.Errorf("%s: got %q; want connection to be rejected", description, result)
+	}
+}
+
+func TestClientCA(t *testing.T) {
+	server := newTestServer(t)
+	clientCA := newTestCA(t, "Client CA")
+	otherCA := newTestCA(t, "Other CA")
+	caPath := filepath.Join(t.TempDir(), "client_ca.pem")
+	writeCAFile(t, caPath, clientCA)
+	valid := clientCA.issue(t, 10, "")
+	untrusted := otherCA.issue(t, 10, "")
+
+	required := server.listen(t, "tls,client_ca="+caPath)
+	expectAccepted(t, server, required, "required, valid certificate", valid)
+	expectRejected(t, server, required, "required, no certificate")
+	expectRejected(t, server, required, "required, untrusted certificate", untrusted)
+
+	ifGiven := server.listen(t, "tls,client_ca="+caPath+",client_auth=verify-if-given")
+	expectAccepted(t, server, ifGiven, "verify-if-given, valid certificate", valid)
+	expectAccepted(t, server, ifGiven, "verify-if-given, no certificate")
+	expectRejected(t, server, ifGiven, "verify-if-given, untrusted certificate", untrusted)
+
+	requested := server.listen(t, "tls,client_auth=request")
+	expectAccepted(t, server, requested, "request, untrusted certificate", untrusted)
+	expectAccepted(t, server, requested, "request, no certificate")
+
+	none := server.listen(t, "tls,client_ca="+caPath+",client_auth=none")
+	expectAccepted(t, server, none, "none, no certificate")
+}
+
+func TestClientCAReload(t *testing.T) {
+	server := newTestServer(t)
+	oldCA := newTestCA(t, "Old CA")
+	newCA := newTestCA(t, "New CA")
+	caPath := filepath.Join(t.TempDir(), "client_ca.pem")
+	writeCAFile(t, caPath, oldCA)
+	addr := server.listen(t, "tls,client_ca="+caPath)
+	oldCert := oldCA.issue(t, 10, "")
+	newCert := newCA.issue(t, 10, "")
+
+	expectAccepted(t, server, addr, "before reload, old CA", oldCert)
+	expectRejected(t, server, addr, "before reload, new CA", newCert)
+
+	writeCAFile(t, caPath, newCA)
+	time.Sleep(1100 * time.Millisecond)
+	expectRejected(t, server, addr, "after reload, old CA", oldCert)
+	expectAccepted(t, server, addr, "after reload, new CA", newCert)
+
+	// If the file becomes invalid, the last good CAs remain in use
+	writeFileChanged(t, caPath, []byte("not a certificate"))
+	time.Sleep(1100 * time.Millisecond)
+	expectAccepted(t, server, addr, "after invalid file, new CA", newCert)
+}
+
+func TestCRL(t *testing.T) {
+	server := newTestServer(t)
+	clientCA := newTestCA(t, "Client CA")
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "client_ca.pem")
+	crlPath := filepath.Join(dir, "client.crl")
+	writeCAFile(t, caPath, clientCA)
+	writeCRL(t, crlPath, clientCA, time.Now().Add(time.Hour), 11)
+	good := clientCA.issue(t, 10, "")
+	revoked := clientCA.issue(t, 11, "")
+
+	addr := server.listen(t, "tls,client_ca="+caPath+",crl="+crlPath)
+	expectAccepted(t, server, addr, "unrevoked certificate", good)
+	expectRejected(t, server, addr, "revoked certificate", revoked)
+
+	// A CRL past its NextUpdate time is not trusted
+	writeCRL(t, crlPath, clientCA, time.Now().Add(-time.Minute))
+	time.Sleep(1100 * time.Millisecond)
+	expectRejected(t, server, addr, "certificate checked against an expired CRL", good)
+}
+
+func TestClientAuthOptionErrors(t *testing.T) {
+	server := newTestServer(t)
+	clientCA := newTestCA(t, "Client CA")
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "client_ca.pem")
+	crlPath := filepath.Join(dir, "client.crl")
+	writeCAFile(t, caPath, clientCA)
+	writeCRL(t, crlPath, clientCA, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		options string
+		wantErr string
+	}{
+		{"client_auth=require", "requires client_ca"},
+		{"client_auth=verify-if-given", "requires client_ca"},
+		{"client_auth=optional", "invalid client_auth"},
+		{"client_ca=" + filepath.Join(dir, "missing.pem"), "invalid client_ca"},
+		{"client_ca=" + crlPath, "invalid client_ca"},
+		{"crl=" + crlPath, "crl but does not verify client certificates"},
+		{"client_ca=" + caPath + ",client_auth=request,crl=" + crlPath, "crl but does not verify client certificates"},
+		{"client_ca=" + caPath + ",crl=" + filepath.Join(dir, "missing.crl"), "invalid crl"},
+		{"client_ca=" + caPath + ",crl=" + caPath, "invalid crl"},
+	}
+	for _, test := range tests {
+		l, err := listener.Open("tls," + test.options + ":" + server.certPath + ":tcp:127.0.0.1:0")
+		if err == nil {
+			l.Close()
+			t.Errorf("opening listener with %s succeeded", test.options)
+		} else if !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("opening listener with %s returned %q; want an error containing %q", test.options, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"src.agwa.name/go-listener/cert"
+)
+
+// Chooses the [tls.Config] for each handshake, which may differ from the
+// listener's base config because of ACME challenges or reloaded resources
+type configSelector struct {
+	base          *tls.Config
+	acmeConfig    *tls.Config // nil unless certificates are obtained with ACME
+	getCAs        cert.GetCertPoolFunc
+	getTicketKeys cert.GetSessionTicketKeysFunc
+
+	mu      sync.Mutex // held while changing current or the ticket keys
+	current atomic.Pointer[selectedConfig]
+
+	ticketMu        sync.Mutex   // held while checking for new ticket keys
+	ticketKeys      *[32]byte    // first of the session ticket keys in use
+	nextTicketCheck atomic.Int64 // when to next check for new ticket keys, in Unix nanoseconds
+}
+
+// How often to check for new session ticket keys
+const ticketKeysCheckInterval = time.Second
+
+// The config to use for clients which aren't doing an ACME challenge
+type selectedConfig struct {
+	cas    *x509.CertPool
+	config *tls.Config // base with cas
+}
+
+func newConfigSelector(base *tls.Config, useACME bool, getCAs cert.GetCertPoolFunc, getTicketKeys cert.GetSessionTicketKeysFunc) *configSelector {
+	selector := &configSelector{
+		base:          base,
+		getCAs:        getCAs,
+		getTicketKeys: getTicketKeys,
+	}
+	if getTicketKeys != nil {
+		selector.ticketMu.Lock()
+		selector.updateTicketKeys()
+		selector.ticketMu.Unlock()
+		selector.nextTicketCheck.Store(time.Now().Add(ticketKeysCheckInterval).UnixNano())
+	}
+	if getCAs != nil {
+		// Select a config now, so there's a good one to fall back
+		// on if the CAs can't be reloaded during a handshake
+		if cas, err := getCAs(); err == nil {
+			selector.selectConfig(cas)
+		}
+	}
+	if useACME {
+		// Negotiate only the ACME TLS-ALPN-01 protocol with clients which
+		// offer it, so that challenges succeed regardless of which
+		// application protocols or client authentication base requires
+		selector.acmeConfig = base.Clone()
+		selector.acmeConfig.GetConfigForClient = nil
+		selector.acmeConfig.NextProtos = []string{acme.ALPNProto}
+		selector.acmeConfig.ClientAuth = tls.NoClientCert
+		selector.acmeConfig.VerifyConnection = nil
+	}
+	return selector
+}
+
+// Report whether the selector ever returns a config other than base
+func (selector *configSelector) needed() bool {
+	return selector.acmeConfig != nil || selector.getCAs != nil || selector.getTicketKeys != nil
+}
+
+func (selector *configSelector) GetConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	if selector.getTicketKeys != nil {
+		selector.checkTicketKeys()
+	}
+	if selector.acmeConfig != nil && slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+		return selector.acmeConfig, nil
+	}
+	if selector.getCAs == nil {
+		return nil, nil
+	}
+
+	// If the CAs can't be reloaded (e.g. because the file is being
+	// replaced), keep using the last good ones
+	cas, err := selector.getCAs()
+	if err != nil {
+		if current := selector.current.Load(); current != nil {
+			return current.config, nil
+		}
+		return nil, err
+	}
+	return selector.selectConfig(cas), nil
+}
+
+// Return a config which uses cas, creating it if cas differs from the CAs
+// of the current config
+func (selector *configSelector) selectConfig(cas *x509.CertPool) *tls.Config {
+	if current := selector.current.Load(); current != nil && current.cas == cas {
+		return current.config
+	}
+
+	selector.mu.Lock()
+	defer selector.mu.Unlock()
+	if current := selector.current.Load(); current != nil && current.cas == cas {
+		return current.config
+	}
+	// Clone copies base's session ticket keys, which are only changed
+	// while holding mu
+	config := selector.base.Clone()
+	config.GetConfigForClient = nil
+	config.ClientCAs = cas
+	selector.current.Store(&selectedConfig{cas: cas, config: config})
+	return config
+}
+
+// If ticketKeysCheckInterval has elapsed and no check is in progress,
+// check for new session ticket keys in the background, so that handshakes
+// never wait for the keys to be loaded
+func (selector *configSelector) checkTicketKeys() {
+	now := time.Now().UnixNano()
+	if now < selector.nextTicketCheck.Load() || !selector.ticketMu.TryLock() {
+		return
+	}
+	selector.nextTicketCheck.Store(now + int64(ticketKeysCheckInterval))
+	go func() {
+		defer selector.ticketMu.Unlock()
+		selector.updateTicketKeys()
+	}()
+}
+
+// If the session ticket keys have changed, start using the new ones.  The
+// caller must hold ticketMu, which ensures that getTicketKeys isn't called
+// again (zeroing the keys it returned) until the keys have been passed to
+// SetSessionTicketKeys.  If the keys can't be reloaded, the old keys remain
+// in use.
+func (selector *configSelector) updateTicketKeys() {
+	keys, err := selector.getTicketKeys()
+	if err != nil || &keys[0] == selector.ticketKeys {
+		return
+	}
+
+	selector.mu.Lock()
+	defer selector.mu.Unlock()
+	selector.base.SetSessionTicketKeys(keys)
+	if current := selector.current.Load(); current != nil {
+		current.config.SetSessionTicketKeys(keys)
+	}
+	selector.ticketKeys = &keys[0]
+}
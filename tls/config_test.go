@@ -0,0 +1,128 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"src.agwa.name/go-listener/cert"
+)
+
+// Write a session ticket keys file containing keys whose bytes are all
+// the given values, and return the keys
+func writeTicketKeys(t *testing.T, path string, values ...byte) [][32]byte {
+	t.Helper()
+	keys := make([][32]byte, len(values))
+	var lines []string
+	for i, value := range values {
+		for j := range keys[i] {
+			keys[i][j] = value
+		}
+		lines = append(lines, base64.StdEncoding.EncodeToString(keys[i][:]))
+	}
+	writeFileChanged(t, path, []byte(strings.Join(lines, "\n")+"\n"))
+	return keys
+}
+
+// Wait for a background check for new ticket keys to finish
+func waitForTicketCheck(selector *configSelector) {
+	selector.ticketMu.Lock()
+	selector.ticketMu.Unlock()
+}
+
+func TestTicketKeyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ticket_keys")
+	oldKeys := writeTicketKeys(t, path, 1)
+	selector := newConfigSelector(new(tls.Config), false, nil, cert.GetSessionTicketKeysFromFile(path))
+	if *selector.ticketKeys != oldKeys[0] {
+		t.Fatalf("selector is using key %x; want %x", *selector.ticketKeys, oldKeys[0])
+	}
+	initialKeys := selector.ticketKeys
+
+	// The file isn't checked again until ticketKeysCheckInterval elapses
+	newKeys := writeTicketKeys(t, path, 2, 1)
+	selector.GetConfigForClient(new(tls.ClientHelloInfo))
+	waitForTicketCheck(selector)
+	if selector.ticketKeys != initialKeys {
+		t.Fatalf("ticket keys were reloaded before the check interval elapsed")
+	}
+
+	time.Sleep(ticketKeysCheckInterval + 100*time.Millisecond)
+	selector.GetConfigForClient(new(tls.ClientHelloInfo))
+	waitForTicketCheck(selector)
+	if selector.ticketKeys == initialKeys || *selector.ticketKeys != newKeys[0] {
+		t.Fatalf("selector is using key %x after rotation; want %x", *selector.ticketKeys, newKeys[0])
+	}
+}
+
+func TestTicketKeysInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ticket_keys")
+	writeFileChanged(t, path, []byte(base64.StdEncoding.EncodeToString(make([]byte, 16))+"\n"))
+	server := newTestServer(t)
+	if _, err := openListener(map[string]interface{}{
+		"session_ticket_keys": path,
+		"cert":                server.certPath,
+		"listener":            map[string]interface{}{"type": "tcp", "address": "127.0.0.1", "port": "0"},
+	}, "", nil, false); err == nil || !strings.Contains(err.Error(), "invalid session_ticket_keys") {
+		t.Errorf("opening listener with invalid session_ticket_keys returned %v; want an error", err)
+	}
+}
+
+func TestSessionTicketKeysShared(t *testing.T) {
+	dir := t.TempDir()
+	sharedPath := filepath.Join(dir, "shared")
+	otherPath := filepath.Join(dir, "other")
+	writeTicketKeys(t, sharedPath, 1)
+	writeTicketKeys(t, otherPath, 2)
+
+	server := newTestServer(t)
+	first := server.listen(t, "tls,session_ticket_keys="+sharedPath)
+	second := server.listen(t, "tls,session_ticket_keys="+sharedPath)
+	other := server.listen(t, "tls,session_ticket_keys="+otherPath)
+
+	config := server.clientConfig()
+	config.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	if state, _, err := dialTLS(first, config); err != nil {
+		t.Fatal(err)
+	} else if state.DidResume {
+		t.Fatal("first connection resumed a session")
+	}
+	if state, _, err := dialTLS(second, config); err != nil {
+		t.Fatal(err)
+	} else if !state.DidResume {
+		t.Error("server with the same ticket keys did not resume the session")
+	}
+	if state, _, err := dialTLS(other, config); err != nil {
+		t.Fatal(err)
+	} else if state.DidResume {
+		t.Error("server with different ticket keys resumed the session")
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+//go:build go1.26
+
+package tls
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestPostQuantumCurvesGo126(t *testing.T) {
+	p, err := parsePolicyParams(map[string]interface{}{"curve_preferences": []interface{}{"SecP256r1MLKEM768", "SecP384r1MLKEM1024"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.curvePreferences) != 2 || p.curvePreferences[0] != tls.SecP256r1MLKEM768 || p.curvePreferences[1] != tls.SecP384r1MLKEM1024 {
+		t.Errorf("curve_preferences parsed as %v", p.curvePreferences)
+	}
+}
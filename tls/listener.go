@@ -35,25 +35,96 @@ package tls // import "src.agwa.name/go-listener/tls"
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net"
+	"slices"
 	"strings"
 
-	"golang.org/x/crypto/acme"
 	"src.agwa.name/go-listener"
 	"src.agwa.name/go-listener/cert"
+	"src.agwa.name/go-listener/internal/params"
+	"src.agwa.name/go-listener/tlsutil"
 )
 
 func init() {
-	listener.RegisterListenerType("tls", openHTTPSListener) // TODO: either remove this listener type or replace it with a generic TLS non-HTTPS listener
+	listener.RegisterListenerType("tls", openTLSListener)
 	listener.RegisterListenerType("https", openHTTPSListener)
+	listener.RegisterListenerType("tls-optional", openOptionalTLSListener)
+	listener.RegisterListenerType("https-optional", openOptionalHTTPSListener)
+}
+
+var httpsProtos = []string{"h2", "http/1.1"}
+
+func openTLSListener(params map[string]interface{}, arg string) (net.Listener, error) {
+	return openListener(params, arg, nil, false)
 }
 
 func openHTTPSListener(params map[string]interface{}, arg string) (net.Listener, error) {
+	return openListener(params, arg, httpsProtos, false)
+}
+
+func openOptionalTLSListener(params map[string]interface{}, arg string) (net.Listener, error) {
+	return openListener(params, arg, nil, true)
+}
+
+func openOptionalHTTPSListener(params map[string]interface{}, arg string) (net.Listener, error) {
+	return openListener(params, arg, httpsProtos, true)
+}
+
+// Open a TLS listener which negotiates the application protocols in
+// defaultProtos unless overridden by the alpn parameter.  If optional is
+// true, connections which don't begin with a TLS ClientHello are accepted
+// as plaintext connections instead of *tls.Conns.
+func openListener(options map[string]interface{}, arg string, defaultProtos []string, optional bool) (net.Listener, error) {
 	var getCertificate cert.GetCertificateFunc
-	var nextProtos = []string{"h2", "http/1.1"}
+	var nextProtos []string
+	var useACME bool
 	var inner net.Listener
 	var err error
 
+	if nextProtos, err = params.Strings(options, "alpn"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid alpn: %w", err)
+	} else if nextProtos == nil {
+		nextProtos = slices.Clone(defaultProtos)
+	}
+	strictALPN, err := params.Bool(options, "strict_alpn")
+	if err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid strict_alpn: %w", err)
+	}
+	if strictALPN && len(nextProtos) == 0 {
+		return nil, errors.New("TLS listener has strict_alpn but no alpn protocols")
+	}
+	clientAuth, err := parseClientAuthParams(options)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := parsePolicyParams(options)
+	if err != nil {
+		return nil, err
+	}
+	handshakeConfig, err := parseHandshakeParams(options)
+	if err != nil {
+		return nil, err
+	}
+	sniffConfig := new(tlsutil.SniffConfig)
+	if sniffConfig.Timeout, err = params.Duration(options, "sniff_timeout"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid sniff_timeout: %w", err)
+	}
+	if sniffConfig.MaxConcurrent, err = params.Int(options, "max_sniffs"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid max_sniffs: %w", err)
+	}
+	if (sniffConfig.Timeout != 0 || sniffConfig.MaxConcurrent != 0) && !optional {
+		return nil, errors.New("TLS listener has sniff_timeout or max_sniffs but TLS is not optional")
+	}
+	var getTicketKeys cert.GetSessionTicketKeysFunc
+	if path, ok := options["session_ticket_keys"].(string); ok {
+		getTicketKeys = cert.GetSessionTicketKeysFromFile(path)
+		if _, err := getTicketKeys(); err != nil {
+			return nil, fmt.Errorf("TLS listener has invalid session_ticket_keys %s: %w", path, err)
+		}
+	}
+
 	if arg != "" {
 		fields := strings.SplitN(arg, ":", 2)
 		if len(fields) < 2 {
@@ -67,7 +138,7 @@ func openHTTPSListener(params map[string]interface{}, arg string) (net.Listener,
 			getCertificate = cert.GetCertificateFromFile(certSpec)
 		} else {
 			getCertificate = cert.GetCertificateAutomatically(strings.Split(certSpec, ","))
-			nextProtos = append(nextProtos, acme.ALPNProto)
+			useACME = true
 		}
 
 		inner, err = listener.Open(innerSpec)
@@ -75,18 +146,18 @@ func openHTTPSListener(params map[string]interface{}, arg string) (net.Listener,
 			return nil, err
 		}
 	} else {
-		if path, ok := params["cert"].(string); ok {
+		if path, ok := options["cert"].(string); ok {
 			getCertificate = cert.GetCertificateFromFile(path)
-		} else if path, ok := params["cert_directory"].(string); ok {
+		} else if path, ok := options["cert_directory"].(string); ok {
 			getCertificate = cert.GetCertificateFromDirectory(path)
-		} else if hostnames, ok := params["autocert_hostnames"].([]string); ok {
+		} else if hostnames, ok := options["autocert_hostnames"].([]string); ok {
 			getCertificate = cert.GetCertificateAutomatically(hostnames)
-			nextProtos = append(nextProtos, acme.ALPNProto)
+			useACME = true
 		} else {
 			return nil, errors.New("certificate not specified for TLS listener")
 		}
 
-		innerSpec, ok := params["listener"].(map[string]interface{})
+		innerSpec, ok := options["listener"].(map[string]interface{})
 		if !ok {
 			return nil, errors.New("inner socket not specified for TLS listener")
 		}
@@ -96,14 +167,90 @@ func openHTTPSListener(params map[string]interface{}, arg string) (net.Listener,
 		}
 	}
 
-	if defaultServerName, ok := params["default_server_name"].(string); ok && defaultServerName != "" {
+	if defaultServerName, ok := options["default_server_name"].(string); ok && defaultServerName != "" {
 		getCertificate = cert.GetCertificateDefaultServerName(defaultServerName, getCertificate)
 	}
 
 	config := &tls.Config{
 		GetCertificate: getCertificate,
 		NextProtos:     nextProtos,
+		ClientAuth:     clientAuth.authType,
+	}
+	policy.apply(config)
+	var verifiers []func(tls.ConnectionState) error
+	if strictALPN {
+		verifiers = append(verifiers, requireALPN)
+	}
+	if clientAuth.getCRLs != nil {
+		verifiers = append(verifiers, checkRevocation(clientAuth.getCRLs))
+	}
+	if len(verifiers) > 0 {
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, verify := range verifiers {
+				if err := verify(state); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if selector := newConfigSelector(config, useACME, clientAuth.getCAs, getTicketKeys); selector.needed() {
+		config.GetConfigForClient = selector.GetConfigForClient
+	}
+
+	var plainListener net.Listener
+	if optional {
+		inner, plainListener = sniffConfig.NewListeners(inner)
 	}
 
-	return tls.NewListener(inner, config), nil
+	var tlsListener net.Listener
+	if handshakeConfig != nil {
+		handshakeConfig.TLSConfig = config
+		tlsListener = handshakeConfig.NewListener(inner)
+	} else {
+		tlsListener = tls.NewListener(inner, config)
+	}
+
+	if plainListener != nil {
+		return newMergedListener(tlsListener, plainListener), nil
+	}
+	return tlsListener, nil
+}
+
+// Return the configuration for completing handshakes before Accept, or nil
+// if the eager_handshake parameter is false
+func parseHandshakeParams(options map[string]interface{}) (*tlsutil.HandshakeConfig, error) {
+	eager, err := params.Bool(options, "eager_handshake")
+	if err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid eager_handshake: %w", err)
+	}
+	config := new(tlsutil.HandshakeConfig)
+	if config.Timeout, err = params.Duration(options, "handshake_timeout"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid handshake_timeout: %w", err)
+	}
+	if config.MaxConcurrent, err = params.Int(options, "max_handshakes"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid max_handshakes: %w", err)
+	}
+	if logErrors, err := params.Bool(options, "log_errors"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid log_errors: %w", err)
+	} else if logErrors {
+		config.ErrorLog = slog.Default()
+	}
+	if !eager {
+		if config.Timeout != 0 || config.MaxConcurrent != 0 || config.ErrorLog != nil {
+			return nil, errors.New("TLS listener has handshake_timeout, max_handshakes, or log_errors without eager_handshake")
+		}
+		return nil, nil
+	}
+	return config, nil
+}
+
+// Reject connections which did not negotiate an application protocol.
+// (crypto/tls already rejects clients which offer ALPN but none of the
+// server's protocols; this also rejects clients which don't offer ALPN.)
+func requireALPN(state tls.ConnectionState) error {
+	if state.NegotiatedProtocol == "" {
+		return errors.New("client did not negotiate an application protocol")
+	}
+	return nil
 }
@@ -0,0 +1,291 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"src.agwa.name/go-listener"
+)
+
+// A certificate authority which issues certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// Issue a certificate with the given serial number.  If dnsName is empty,
+// the certificate is for client authentication; otherwise, it is for a
+// server with that name.
+func (ca *testCA) issue(t *testing.T, serial int64, dnsName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if dnsName != "" {
+		template.DNSNames = []string{dnsName}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// Write the certificates of cas to a PEM file at path
+func writeCAFile(t *testing.T, path string, cas ...*testCA) {
+	t.Helper()
+	var data []byte
+	for _, ca := range cas {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})...)
+	}
+	writeFileChanged(t, path, data)
+}
+
+// Write a certificate and its private key to path in the format accepted
+// by cert.LoadCertificate
+func writeCertificateFile(t *testing.T, path string, certificate tls.Certificate) {
+	t.Helper()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	for _, der := range certificate.Certificate {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	writeFileChanged(t, path, data)
+}
+
+// Write data to path, making sure that the modification time changes so
+// that the file is reloaded even if its size doesn't change
+func writeFileChanged(t *testing.T, path string, data []byte) {
+	t.Helper()
+	var mtime time.Time
+	if info, err := os.Stat(path); err == nil {
+		mtime = info.ModTime().Add(time.Second)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !mtime.IsZero() {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// A TLS server for tests, which uses a certificate for "localhost"
+// issued by its own CA
+type testServer struct {
+	ca       *testCA
+	certPath string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	server := &testServer{
+		ca:       newTestCA(t, "Server CA"),
+		certPath: filepath.Join(t.TempDir(), "server.pem"),
+	}
+	writeCertificateFile(t, server.certPath, server.ca.issue(t, 2, "localhost"))
+	return server
+}
+
+// Open a listener of the given type and options (e.g. "tls,alpn=imap")
+// using the server's certificate, and serve connections on it until the
+// test ends.  TLS connections are sent "tls" followed by the negotiated
+// protocol, if any.  Plaintext connections are sent "plain" after the
+// client sends its first byte.  The listener's address is returned.
+func (server *testServer) listen(t *testing.T, typeAndOptions string) string {
+	t.Helper()
+	l, err := listener.Open(typeAndOptions + ":" + server.certPath + ":tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConn(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func serveTestConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		io.WriteString(conn, "tls "+tlsConn.ConnectionState().NegotiatedProtocol)
+	} else {
+		if _, err := conn.Read(make([]byte, 1)); err != nil {
+			return
+		}
+		io.WriteString(conn, "plain")
+	}
+}
+
+// Return a client config which trusts the server's certificate and
+// presents clientCert, if specified, even if it wasn't issued by a CA
+// which the server accepts
+func (server *testServer) clientConfig(clientCert ...tls.Certificate) *tls.Config {
+	return &tls.Config{
+		ServerName: "localhost",
+		RootCAs:    server.ca.pool(),
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if len(clientCert) == 0 {
+				return new(tls.Certificate), nil
+			}
+			return &clientCert[0], nil
+		},
+	}
+}
+
+// Connect to addr with TLS and return the connection state and what the
+// server sent, or an error if the handshake failed or the server rejected
+// the connection
+func dialTLS(addr string, config *tls.Config) (tls.ConnectionState, string, error) {
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 5 * time.Second}, Config: config}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return tls.ConnectionState{}, "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		return tls.ConnectionState{}, "", err
+	}
+	return conn.(*tls.Conn).ConnectionState(), string(response), nil
+}
+
+func TestStrictALPN(t *testing.T) {
+	server := newTestServer(t)
+	strict := server.listen(t, "tls,alpn=imap,alpn=imaps,strict_alpn")
+	lenient := server.listen(t, "tls,alpn=imap")
+
+	tests := []struct {
+		addr       string
+		protos     []string
+		wantResult string // empty if the connection should be rejected
+	}{
+		{strict, []string{"pop3", "imaps"}, "tls imaps"},
+		{strict, []string{"imap"}, "tls imap"},
+		{strict, []string{"pop3"}, ""},
+		{strict, nil, ""},
+		{lenient, []string{"imap"}, "tls imap"},
+		{lenient, []string{"pop3"}, ""},
+		{lenient, nil, "tls "},
+	}
+	for _, test := range tests {
+		config := server.clientConfig()
+		config.NextProtos = test.protos
+		_, result, err := dialTLS(test.addr, config)
+		strictness := "strict"
+		if test.addr == lenient {
+			strictness = "lenient"
+		}
+		if test.wantResult == "" && err == nil {
+			t.Errorf("%s server accepted client offering %v", strictness, test.protos)
+		} else if test.wantResult != "" && (err != nil || result != test.wantResult) {
+			t.Errorf("%s server with client offering %v: got %q, %v; want %q", strictness, test.protos, result, err, test.wantResult)
+		}
+	}
+}
+
+func TestStrictALPNWithoutProtocols(t *testing.T) {
+	server := newTestServer(t)
+	if l, err := listener.Open("tls,strict_alpn:" + server.certPath + ":tcp:127.0.0.1:0"); err == nil {
+		l.Close()
+		t.Error("opening listener with strict_alpn but no alpn succeeded")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// A listener which accepts connections from several listeners.  Since the
+// connections can be of different types (e.g. *tls.Conn from one listener
+// and plaintext connections from another), callers which need to tell them
+// apart must use a type assertion.
+type mergedListener struct {
+	listeners []net.Listener
+	conns     chan net.Conn
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newMergedListener(listeners ...net.Listener) *mergedListener {
+	merged := &mergedListener{
+		listeners: listeners,
+		conns:     make(chan net.Conn),
+		errors:    make(chan error),
+		done:      make(chan struct{}),
+	}
+	for _, l := range listeners {
+		go merged.handleAccepts(l)
+	}
+	return merged
+}
+
+func (merged *mergedListener) handleAccepts(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			select {
+			case merged.errors <- err:
+			case <-merged.done:
+				return
+			}
+		} else {
+			select {
+			case merged.conns <- conn:
+			case <-merged.done:
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (merged *mergedListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-merged.conns:
+		return conn, nil
+	case err := <-merged.errors:
+		return nil, err
+	case <-merged.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (merged *mergedListener) Close() error {
+	err := net.ErrClosed
+	merged.closeOnce.Do(func() {
+		close(merged.done)
+		err = nil
+		for _, l := range merged.listeners {
+			if closeErr := l.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
+
+func (merged *mergedListener) Addr() net.Addr {
+	return merged.listeners[0].Addr()
+}
@@ -0,0 +1,121 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"src.agwa.name/go-listener"
+)
+
+// Connect to addr without TLS, send a byte, and return what the server sent
+func dialPlain(addr string) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("x")); err != nil {
+		return "", err
+	}
+	response, err := io.ReadAll(conn)
+	return string(response), err
+}
+
+func TestOptionalTLS(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		typeAndOptions string
+		wantTLS        string
+	}{
+		{"tls-optional", "tls "},
+		{"https-optional", "tls h2"},
+		{"tls-optional,alpn=imap,eager_handshake,sniff_timeout=2s,max_sniffs=10", "tls imap"},
+	}
+	for _, test := range tests {
+		addr := server.listen(t, test.typeAndOptions)
+
+		config := server.clientConfig()
+		config.NextProtos = []string{"h2", "imap"}
+		if _, result, err := dialTLS(addr, config); err != nil || result != test.wantTLS {
+			t.Errorf("%s: TLS client got %q, %v; want %q", test.typeAndOptions, result, err, test.wantTLS)
+		}
+		if result, err := dialPlain(addr); err != nil || result != "plain" {
+			t.Errorf("%s: plaintext client got %q, %v; want %q", test.typeAndOptions, result, err, "plain")
+		}
+	}
+}
+
+func TestOptionalTLSClose(t *testing.T) {
+	server := newTestServer(t)
+	l, err := listener.Open("tls-optional:" + server.certPath + ":tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.(*mergedListener); !ok {
+		t.Errorf("tls-optional returned a %T; want a *mergedListener", l)
+	}
+	addr := l.Addr().String()
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if conn != nil {
+			conn.Close()
+		}
+		accepted <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	l.Close()
+	if err := <-accepted; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept returned %v after Close; want net.ErrClosed", err)
+	}
+
+	// Closing the merged listener closes the inner listener
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("inner listener is still accepting connections after Close")
+	}
+}
+
+func TestSniffOptionsRequireOptional(t *testing.T) {
+	server := newTestServer(t)
+	for _, options := range []string{"sniff_timeout=1s", "max_sniffs=10"} {
+		l, err := listener.Open("tls," + options + ":" + server.certPath + ":tcp:127.0.0.1:0")
+		if err == nil {
+			l.Close()
+			t.Errorf("opening tls listener with %s succeeded", options)
+		} else if !strings.Contains(err.Error(), "TLS is not optional") {
+			t.Errorf("opening tls listener with %s returned %q", options, err)
+		}
+	}
+}
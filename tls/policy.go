@@ -0,0 +1,201 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"src.agwa.name/go-listener/internal/params"
+)
+
+// Protocol versions, cipher suites, and key exchanges allowed by a TLS
+// listener.  Zero values mean Go's defaults.
+type policy struct {
+	minVersion       uint16
+	maxVersion       uint16
+	cipherSuites     []uint16
+	curvePreferences []tls.CurveID
+}
+
+// Named policies following Mozilla's server side TLS guidelines
+// (https://wiki.mozilla.org/Security/Server_Side_TLS).  Key exchanges are
+// left at Go's defaults, which are the guidelines' curves plus any
+// post-quantum hybrids supported by the toolchain.  TLS 1.3 cipher suites
+// are not configurable in Go.
+var policyPresets = map[string]policy{
+	"modern": {
+		minVersion: tls.VersionTLS13,
+	},
+	"intermediate": {
+		minVersion: tls.VersionTLS12,
+		cipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	},
+	"compat": {
+		minVersion: tls.VersionTLS10,
+		cipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		},
+	},
+}
+
+var versionNames = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Key exchange names accepted by curve_preferences.  Post-quantum hybrids
+// are added by files built with toolchains that support them.
+var curveNames = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P-256":  tls.CurveP256,
+	"P-384":  tls.CurveP384,
+	"P-521":  tls.CurveP521,
+}
+
+func parsePolicyParams(options map[string]interface{}) (*policy, error) {
+	var p policy
+
+	if name, ok := options["policy"].(string); ok {
+		preset, ok := policyPresets[name]
+		if !ok {
+			return nil, fmt.Errorf("TLS listener has unknown policy %q; must be modern, intermediate, or compat", name)
+		}
+		p = preset
+	}
+
+	var err error
+	if p.minVersion, err = parseVersionParam(options, "min_version", p.minVersion); err != nil {
+		return nil, err
+	}
+	if p.maxVersion, err = parseVersionParam(options, "max_version", p.maxVersion); err != nil {
+		return nil, err
+	}
+	if p.minVersion != 0 && p.maxVersion != 0 && p.minVersion > p.maxVersion {
+		return nil, errors.New("TLS listener has min_version greater than max_version")
+	}
+
+	if names, err := params.Strings(options, "cipher_suites"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid cipher_suites: %w", err)
+	} else if names != nil {
+		p.cipherSuites = make([]uint16, len(names))
+		for i, name := range names {
+			if p.cipherSuites[i], err = lookupCipherSuite(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if names, err := params.Strings(options, "curve_preferences"); err != nil {
+		return nil, fmt.Errorf("TLS listener has invalid curve_preferences: %w", err)
+	} else if names != nil {
+		p.curvePreferences = make([]tls.CurveID, len(names))
+		for i, name := range names {
+			curve, ok := curveNames[name]
+			if !ok {
+				return nil, fmt.Errorf("TLS listener has unknown curve %q (this version of Go supports %s)", name, supportedCurveNames())
+			}
+			p.curvePreferences[i] = curve
+		}
+	}
+
+	return &p, nil
+}
+
+func parseVersionParam(params map[string]interface{}, name string, defaultVersion uint16) (uint16, error) {
+	str, ok := params[name].(string)
+	if !ok {
+		if _, present := params[name]; present {
+			return 0, fmt.Errorf("TLS listener has invalid %s: must be a string", name)
+		}
+		return defaultVersion, nil
+	}
+	version, ok := versionNames[str]
+	if !ok {
+		return 0, fmt.Errorf("TLS listener has unknown %s %q; must be 1.0, 1.1, 1.2, or 1.3", name, str)
+	}
+	return version, nil
+}
+
+func lookupCipherSuite(name string) (uint16, error) {
+	for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, suite := range suites {
+			if suite.Name != name {
+				continue
+			}
+			if len(suite.SupportedVersions) == 1 && suite.SupportedVersions[0] == tls.VersionTLS13 {
+				return 0, fmt.Errorf("TLS listener has TLS 1.3 cipher suite %s, but TLS 1.3 cipher suites are not configurable", name)
+			}
+			return suite.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("TLS listener has unknown cipher suite %q", name)
+}
+
+func supportedCurveNames() string {
+	names := make([]string, 0, len(curveNames))
+	for name := range curveNames {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+func (p *policy) apply(config *tls.Config) {
+	config.MinVersion = p.minVersion
+	config.MaxVersion = p.maxVersion
+	config.CipherSuites = p.cipherSuites
+	config.CurvePreferences = p.curvePreferences
+}
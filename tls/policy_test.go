@@ -0,0 +1,183 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tls
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicyParams(t *testing.T) {
+	tests := []struct {
+		options map[string]interface{}
+		want    policy
+		wantErr string
+	}{
+		{
+			options: map[string]interface{}{},
+			want:    policy{},
+		},
+		{
+			options: map[string]interface{}{"policy": "modern"},
+			want:    policyPresets["modern"],
+		},
+		{
+			options: map[string]interface{}{"policy": "intermediate"},
+			want:    policy{minVersion: tls.VersionTLS12, cipherSuites: policyPresets["intermediate"].cipherSuites},
+		},
+		{
+			options: map[string]interface{}{"policy": "compat"},
+			want:    policy{minVersion: tls.VersionTLS10, cipherSuites: policyPresets["compat"].cipherSuites},
+		},
+		{
+			options: map[string]interface{}{"policy": "modern", "min_version": "1.2"},
+			want:    policy{minVersion: tls.VersionTLS12},
+		},
+		{
+			options: map[string]interface{}{"min_version": "1.0", "max_version": "1.1"},
+			want:    policy{minVersion: tls.VersionTLS10, maxVersion: tls.VersionTLS11},
+		},
+		{
+			options: map[string]interface{}{"min_version": "1.3", "max_version": "1.3"},
+			want:    policy{minVersion: tls.VersionTLS13, maxVersion: tls.VersionTLS13},
+		},
+		{
+			options: map[string]interface{}{"cipher_suites": "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			want:    policy{cipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}},
+		},
+		{
+			options: map[string]interface{}{"policy": "intermediate", "cipher_suites": []interface{}{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_RSA_WITH_3DES_EDE_CBC_SHA"}},
+			want:    policy{minVersion: tls.VersionTLS12, cipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA}},
+		},
+		{
+			options: map[string]interface{}{"curve_preferences": []interface{}{"P-256", "X25519", "P-384", "P-521"}},
+			want:    policy{curvePreferences: []tls.CurveID{tls.CurveP256, tls.X25519, tls.CurveP384, tls.CurveP521}},
+		},
+		{
+			options: map[string]interface{}{"policy": "strict"},
+			wantErr: `unknown policy "strict"`,
+		},
+		{
+			options: map[string]interface{}{"min_version": "1.4"},
+			wantErr: `unknown min_version "1.4"`,
+		},
+		{
+			options: map[string]interface{}{"max_version": "TLS1.2"},
+			wantErr: `unknown max_version "TLS1.2"`,
+		},
+		{
+			options: map[string]interface{}{"max_version": json.Number("1")},
+			wantErr: "invalid max_version: must be a string",
+		},
+		{
+			options: map[string]interface{}{"min_version": "1.3", "max_version": "1.2"},
+			wantErr: "min_version greater than max_version",
+		},
+		{
+			options: map[string]interface{}{"policy": "modern", "max_version": "1.2"},
+			wantErr: "min_version greater than max_version",
+		},
+		{
+			options: map[string]interface{}{"cipher_suites": "TLS_AES_128_GCM_SHA256"},
+			wantErr: "TLS 1.3 cipher suites are not configurable",
+		},
+		{
+			options: map[string]interface{}{"cipher_suites": []interface{}{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_BOGUS"}},
+			wantErr: `unknown cipher suite "TLS_BOGUS"`,
+		},
+		{
+			options: map[string]interface{}{"cipher_suites": true},
+			wantErr: "invalid cipher_suites",
+		},
+		{
+			options: map[string]interface{}{"curve_preferences": "P-192"},
+			wantErr: `unknown curve "P-192"`,
+		},
+	}
+	for _, test := range tests {
+		got, err := parsePolicyParams(test.options)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("parsePolicyParams(%v) returned error %v; want %q", test.options, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePolicyParams(%v) returned error %v", test.options, err)
+		} else if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("parsePolicyParams(%v) = %+v; want %+v", test.options, *got, test.want)
+		}
+	}
+}
+
+func TestPolicyPresets(t *testing.T) {
+	for name, preset := range policyPresets {
+		for _, id := range preset.cipherSuites {
+			if _, err := lookupCipherSuite(tls.CipherSuiteName(id)); err != nil {
+				t.Errorf("%s policy: %s", name, err)
+			}
+		}
+		if preset.minVersion == 0 {
+			t.Errorf("%s policy has no minimum version", name)
+		}
+	}
+	if suites := policyPresets["modern"].cipherSuites; len(suites) != 0 {
+		t.Errorf("modern policy has TLS 1.2 cipher suites %v", suites)
+	}
+}
+
+func TestCurveNames(t *testing.T) {
+	for name, curve := range curveNames {
+		p, err := parsePolicyParams(map[string]interface{}{"curve_preferences": name})
+		if err != nil {
+			t.Errorf("curve %s: %s", name, err)
+		} else if !reflect.DeepEqual(p.curvePreferences, []tls.CurveID{curve}) {
+			t.Errorf("curve %s parsed as %v; want %v", name, p.curvePreferences, curve)
+		}
+		if !strings.Contains(supportedCurveNames(), name) {
+			t.Errorf("supportedCurveNames() doesn't include %s", name)
+		}
+	}
+}
+
+func TestPolicyApply(t *testing.T) {
+	p := policy{
+		minVersion:       tls.VersionTLS12,
+		maxVersion:       tls.VersionTLS13,
+		cipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		curvePreferences: []tls.CurveID{tls.X25519},
+	}
+	config := new(tls.Config)
+	p.apply(config)
+	if config.MinVersion != p.minVersion || config.MaxVersion != p.maxVersion ||
+		!reflect.DeepEqual(config.CipherSuites, p.cipherSuites) ||
+		!reflect.DeepEqual(config.CurvePreferences, p.curvePreferences) {
+		t.Errorf("apply produced config with versions %x-%x, cipher suites %v, and curves %v", config.MinVersion, config.MaxVersion, config.CipherSuites, config.CurvePreferences)
+	}
+}
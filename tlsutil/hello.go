@@ -39,6 +39,7 @@ type peekedConn struct {
 	reader io.Reader
 }
 func (conn peekedConn) Read(p []byte) (int, error) { return conn.reader.Read(p) }
+func (conn peekedConn) NetConn() net.Conn          { return conn.Conn }
 
 func PeekClientHelloFromConn(conn net.Conn) (*tls.ClientHelloInfo, net.Conn, error) {
 	hello, reader, err := PeekClientHello(conn)
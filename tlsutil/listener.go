@@ -0,0 +1,255 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// HandshakeConfig contains options for a TLS listener which completes the
+// handshake before returning connections from Accept
+type HandshakeConfig struct {
+	// The TLS configuration for the server side of the handshake
+	TLSConfig *tls.Config
+
+	// How long to wait for the handshake to complete.  If zero, 10 seconds
+	// is used.
+	Timeout time.Duration
+
+	// The maximum number of connections which are being handshaken or are
+	// waiting to be returned by Accept.  When reached, the listener stops
+	// accepting connections from the inner listener until a handshake fails
+	// or Accept returns a connection.  Zero means no limit.
+	MaxConcurrent int
+
+	// Called for each connection whose handshake fails.  The connection is
+	// closed and not returned from Accept.  Must be safe for concurrent use.
+	ErrorFunc func(*HandshakeError)
+
+	// If non-nil, failed handshakes are logged to ErrorLog
+	ErrorLog *slog.Logger
+}
+
+// HandshakeError describes a failed handshake
+type HandshakeError struct {
+	RemoteAddr net.Addr
+	ServerName string // from the client's SNI extension, or "" if not received
+	Err        error
+}
+
+func (err *HandshakeError) Error() string {
+	if err.ServerName == "" {
+		return fmt.Sprintf("TLS handshake with %s failed: %s", err.RemoteAddr, err.Err)
+	}
+	return fmt.Sprintf("TLS handshake with %s for %s failed: %s", err.RemoteAddr, err.ServerName, err.Err)
+}
+
+func (err *HandshakeError) Unwrap() error {
+	return err.Err
+}
+
+type handshakeListener struct {
+	inner      net.Listener
+	config     HandshakeConfig
+	tlsConfig  *tls.Config // TLSConfig with GetConfigForClient wrapped by getConfigForClient
+	conns      chan *tls.Conn
+	errors     chan error
+	ctx        context.Context // canceled when the listener is closed
+	cancel     context.CancelFunc
+	handshakes chan struct{} // semaphore limiting concurrent handshakes, or nil
+	closeMu    sync.Mutex
+}
+
+// NewListener creates a [net.Listener] which accepts connections from inner
+// and performs the TLS handshake on them in the background.  Accept only
+// returns connections whose handshake succeeded, as *[tls.Conn]s.  Unlike
+// [crypto/tls.NewListener], a slow or failed handshake doesn't occupy the
+// goroutine that calls Accept or serves the connection.
+func (config *HandshakeConfig) NewListener(inner net.Listener) net.Listener {
+	listener := &handshakeListener{
+		inner:  inner,
+		config: *config,
+		conns:  make(chan *tls.Conn),
+		errors: make(chan error),
+	}
+	listener.ctx, listener.cancel = context.WithCancel(context.Background())
+	if listener.config.Timeout == 0 {
+		listener.config.Timeout = 10 * time.Second
+	}
+	if listener.config.MaxConcurrent > 0 {
+		listener.handshakes = make(chan struct{}, listener.config.MaxConcurrent)
+	}
+	if listener.config.TLSConfig != nil {
+		listener.tlsConfig = listener.config.TLSConfig.Clone()
+		listener.tlsConfig.GetConfigForClient = listener.getConfigForClient
+	}
+	go listener.handleAccepts()
+	return listener
+}
+
+func (listener *handshakeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-listener.conns:
+		return conn, nil
+	case err := <-listener.errors:
+		return nil, err
+	case <-listener.ctx.Done():
+		return nil, net.ErrClosed
+	}
+}
+
+func (listener *handshakeListener) Close() error {
+	listener.closeMu.Lock()
+	defer listener.closeMu.Unlock()
+
+	select {
+	case <-listener.ctx.Done():
+		return net.ErrClosed
+	default:
+		listener.cancel() // also aborts handshakes in progress
+		return listener.inner.Close()
+	}
+}
+
+func (listener *handshakeListener) Addr() net.Addr {
+	return listener.inner.Addr()
+}
+
+func (listener *handshakeListener) handleAccepts() {
+	for {
+		if listener.handshakes != nil {
+			select {
+			case listener.handshakes <- struct{}{}:
+			case <-listener.ctx.Done():
+				return
+			}
+		}
+		conn, err := listener.inner.Accept()
+		if err != nil {
+			listener.releaseHandshake()
+		}
+		if errors.Is(err, net.ErrClosed) {
+			break
+		} else if err != nil {
+			if !listener.sendError(err) {
+				break
+			}
+		} else {
+			go listener.handleConnection(conn)
+		}
+	}
+}
+
+func (listener *handshakeListener) handleConnection(conn net.Conn) {
+	// The handshake slot is held until Accept returns the connection, so
+	// that connections waiting for Accept count towards MaxConcurrent
+	defer listener.releaseHandshake()
+
+	var serverName string
+	ctx := context.WithValue(listener.ctx, serverNameKey{}, &serverName)
+	ctx, cancel := context.WithTimeout(ctx, listener.config.Timeout)
+	tlsConn := tls.Server(conn, listener.tlsConfig)
+	err := tlsConn.HandshakeContext(ctx)
+	cancel()
+	if err != nil {
+		tlsConn.Close()
+		listener.reportError(&HandshakeError{
+			RemoteAddr: conn.RemoteAddr(),
+			ServerName: serverName,
+			Err:        err,
+		})
+		return
+	}
+	if !listener.sendConn(tlsConn) {
+		tlsConn.Close()
+	}
+}
+
+// Context key whose value is a *string in which getConfigForClient records
+// the server name sent by the client, so it can be reported even if the
+// handshake fails before crypto/tls records it
+type serverNameKey struct{}
+
+// Record the server name sent by the client, and return the config for the
+// rest of the handshake: the one chosen by TLSConfig.GetConfigForClient if
+// it's set and returns one, or else TLSConfig itself, so that changes made
+// to TLSConfig after the listener was created (e.g. with
+// SetSessionTicketKeys) take effect
+func (listener *handshakeListener) getConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	if serverName, ok := hello.Context().Value(serverNameKey{}).(*string); ok {
+		*serverName = hello.ServerName
+	}
+	if getConfigForClient := listener.config.TLSConfig.GetConfigForClient; getConfigForClient != nil {
+		if config, err := getConfigForClient(hello); config != nil || err != nil {
+			return config, err
+		}
+	}
+	return listener.config.TLSConfig, nil
+}
+
+func (listener *handshakeListener) releaseHandshake() {
+	if listener.handshakes != nil {
+		<-listener.handshakes
+	}
+}
+
+func (listener *handshakeListener) reportError(err *HandshakeError) {
+	if listener.ctx.Err() != nil {
+		// Handshakes aborted by Close aren't worth reporting
+		return
+	}
+	if listener.config.ErrorFunc != nil {
+		listener.config.ErrorFunc(err)
+	}
+	if listener.config.ErrorLog != nil {
+		listener.config.ErrorLog.Warn("TLS handshake failed", "remote_addr", err.RemoteAddr.String(), "server_name", err.ServerName, "error", err.Err)
+	}
+}
+
+func (listener *handshakeListener) sendError(err error) bool {
+	select {
+	case listener.errors <- err:
+		return true
+	case <-listener.ctx.Done():
+		return false
+	}
+}
+
+func (listener *handshakeListener) sendConn(conn *tls.Conn) bool {
+	select {
+	case listener.conns <- conn:
+		return true
+	case <-listener.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tlsutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// Return a server config with a self-signed certificate for example.com
+func testServerConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+var testClientConfig = &tls.Config{ServerName: "example.com", InsecureSkipVerify: true}
+
+func listenHandshake(t *testing.T, config *HandshakeConfig) net.Listener {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := config.NewListener(inner)
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+// Start a TLS handshake with addr in the background, returning a channel
+// which receives its result
+func dialTLS(t *testing.T, addr net.Addr) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() {
+		conn, err := tls.Dial("tcp", addr.String(), testClientConfig)
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+		}
+		result <- err
+	}()
+	return result
+}
+
+func TestHandshakeListener(t *testing.T) {
+	listener := listenHandshake(t, &HandshakeConfig{TLSConfig: testServerConfig(t)})
+	if err := <-dialTLS(t, listener.Addr()); err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		t.Fatalf("Accept returned %T; want *tls.Conn", conn)
+	}
+	if !tlsConn.ConnectionState().HandshakeComplete {
+		t.Error("Accept returned connection before handshake completed")
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	errs := make(chan *HandshakeError, 10)
+	listener := listenHandshake(t, &HandshakeConfig{
+		TLSConfig: testServerConfig(t),
+		Timeout:   100 * time.Millisecond,
+		ErrorFunc: func(err *HandshakeError) { errs <- err },
+	})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("ErrorFunc called with %q; want a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ErrorFunc was not called for the timed out handshake")
+	}
+}
+
+func TestHandshakeMaxConcurrent(t *testing.T) {
+	listener := listenHandshake(t, &HandshakeConfig{
+		TLSConfig:     testServerConfig(t),
+		MaxConcurrent: 1,
+	})
+
+	// The first connection completes its handshake but isn't accepted, so
+	// it occupies the only slot and the second handshake can't start
+	if err := <-dialTLS(t, listener.Addr()); err != nil {
+		t.Fatalf("first handshake failed: %s", err)
+	}
+	second := dialTLS(t, listener.Addr())
+	select {
+	case err := <-second:
+		t.Fatalf("second handshake finished (%v) while the limit was reached", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	for i := 0; i < 2; i++ {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("second handshake failed: %s", err)
+	}
+}
+
+func TestHandshakeErrorServerName(t *testing.T) {
+	config := testServerConfig(t)
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return nil, errors.New("no config for you")
+	}
+	errs := make(chan *HandshakeError, 10)
+	listener := listenHandshake(t, &HandshakeConfig{
+		TLSConfig: config,
+		ErrorFunc: func(err *HandshakeError) { errs <- err },
+	})
+
+	if err := <-dialTLS(t, listener.Addr()); err == nil {
+		t.Fatal("handshake succeeded")
+	}
+	select {
+	case err := <-errs:
+		if err.ServerName != "example.com" {
+			t.Errorf("HandshakeError has ServerName %q; want example.com", err.ServerName)
+		}
+		if err.Err.Error() != "no config for you" {
+			t.Errorf("HandshakeError has Err %q", err.Err)
+		}
+		if err.RemoteAddr == nil {
+			t.Error("HandshakeError has no RemoteAddr")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ErrorFunc was not called")
+	}
+}
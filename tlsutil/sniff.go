@@ -0,0 +1,229 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tlsutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// SniffConfig contains options for splitting a listener into TLS and
+// plaintext listeners based on the first byte sent by each client.
+//
+// Connections are deliberately classified by their first byte alone,
+// rather than by parsing the whole ClientHello with
+// [PeekClientHelloFromConn].  No plaintext protocol in practical use
+// begins with 0x16, and waiting for a complete ClientHello would delay
+// plaintext clients which send short requests and require buffering the
+// whole ClientHello of every TLS connection.  Malformed ClientHellos are
+// rejected by the TLS handshake instead.
+type SniffConfig struct {
+	// How long to wait for the client to send its first byte.  Clients
+	// which send nothing within this time are closed.  If zero, one
+	// second is used.
+	Timeout time.Duration
+
+	// The maximum number of connections which are waiting for their first
+	// byte or waiting to be returned by Accept.  When reached, the
+	// listener stops accepting connections from the inner listener until
+	// one of them is closed or returned by Accept.  Zero means no limit.
+	MaxConcurrent int
+}
+
+// The first byte of a TLS handshake record, which begins every ClientHello
+const recordTypeHandshake = 0x16
+
+type sniffer struct {
+	inner   net.Listener
+	timeout time.Duration
+	errors  chan error
+	ctx     context.Context // canceled when both sub-listeners are closed
+	cancel  context.CancelFunc
+	sniffs  chan struct{} // semaphore limiting concurrent connections, or nil
+
+	mu   sync.Mutex
+	open int // number of sub-listeners which haven't been closed
+}
+
+type sniffedListener struct {
+	sniffer   *sniffer
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewListeners splits inner into two listeners: one which accepts
+// connections that begin with a TLS handshake record, and one which
+// accepts connections that begin with any other byte.  Connections which
+// send nothing before the timeout, or fail before sending anything, are
+// closed.  Connections accepted from tlsListener have not been wrapped
+// with TLS yet; pass tlsListener to [crypto/tls.NewListener] or
+// [HandshakeConfig.NewListener], which reports malformed ClientHellos.
+// The byte peeked from each connection is returned by its Read method.
+//
+// Both listeners must be served or closed.  inner is closed when both
+// listeners are closed.
+func (config *SniffConfig) NewListeners(inner net.Listener) (tlsListener, plainListener net.Listener) {
+	s := &sniffer{
+		inner:   inner,
+		timeout: config.Timeout,
+		errors:  make(chan error),
+		open:    2,
+	}
+	if s.timeout == 0 {
+		s.timeout = 1 * time.Second
+	}
+	if config.MaxConcurrent > 0 {
+		s.sniffs = make(chan struct{}, config.MaxConcurrent)
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	tlsSub, plainSub := s.newSubListener(), s.newSubListener()
+	go s.handleAccepts(tlsSub, plainSub)
+	return tlsSub, plainSub
+}
+
+func (s *sniffer) newSubListener() *sniffedListener {
+	return &sniffedListener{
+		sniffer: s,
+		conns:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (s *sniffer) handleAccepts(tlsSub, plainSub *sniffedListener) {
+	for {
+		if s.sniffs != nil {
+			select {
+			case s.sniffs <- struct{}{}:
+			case <-s.ctx.Done():
+				return
+			}
+		}
+		conn, err := s.inner.Accept()
+		if err != nil {
+			s.releaseSniff()
+		}
+		if errors.Is(err, net.ErrClosed) {
+			break
+		} else if err != nil {
+			select {
+			case s.errors <- err:
+			case <-s.ctx.Done():
+				return
+			}
+		} else {
+			go s.handleConnection(conn, tlsSub, plainSub)
+		}
+	}
+}
+
+func (s *sniffer) handleConnection(conn net.Conn, tlsSub, plainSub *sniffedListener) {
+	// The slot is held until Accept returns the connection, so that
+	// connections waiting for Accept count towards MaxConcurrent
+	defer s.releaseSniff()
+
+	isTLS, conn, err := sniff(conn, s.timeout)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	sub := plainSub
+	if isTLS {
+		sub = tlsSub
+	}
+	select {
+	case sub.conns <- conn:
+	case <-sub.closed:
+		conn.Close()
+	case <-s.ctx.Done():
+		conn.Close()
+	}
+}
+
+func (s *sniffer) releaseSniff() {
+	if s.sniffs != nil {
+		<-s.sniffs
+	}
+}
+
+// Report whether conn begins with a TLS handshake record, and return a
+// connection which replays the byte read from conn.  An error is returned
+// if conn doesn't send anything before the timeout.
+func sniff(conn net.Conn, timeout time.Duration) (bool, net.Conn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false, conn, err
+	}
+	var first [1]byte
+	if _, err := io.ReadFull(conn, first[:]); err != nil {
+		return false, conn, err
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return false, conn, err
+	}
+	peeked := peekedConn{Conn: conn, reader: io.MultiReader(bytes.NewReader(first[:]), conn)}
+	return first[0] == recordTypeHandshake, peeked, nil
+}
+
+// Called when a sub-listener is closed
+func (s *sniffer) release() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open--
+	if s.open > 0 {
+		return nil
+	}
+	s.cancel()
+	return s.inner.Close()
+}
+
+func (listener *sniffedListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-listener.conns:
+		return conn, nil
+	case err := <-listener.sniffer.errors:
+		return nil, err
+	case <-listener.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (listener *sniffedListener) Close() error {
+	err := net.ErrClosed
+	listener.closeOnce.Do(func() {
+		close(listener.closed)
+		err = listener.sniffer.release()
+	})
+	return err
+}
+
+func (listener *sniffedListener) Addr() net.Addr {
+	return listener.sniffer.inner.Addr()
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package tlsutil
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSniffTLSAndPlaintext(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sniffConfig := &SniffConfig{Timeout: 100 * time.Millisecond}
+	tlsInner, plainListener := sniffConfig.NewListeners(inner)
+	defer plainListener.Close()
+	tlsListener := (&HandshakeConfig{TLSConfig: testServerConfig(t)}).NewListener(tlsInner)
+	defer tlsListener.Close()
+
+	// A client which sends nothing is closed rather than treated as plaintext
+	silent, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	if err := <-dialTLS(t, inner.Addr()); err != nil {
+		t.Fatalf("TLS handshake failed: %s", err)
+	}
+	tlsConn, err := tlsListener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tlsConn.Close()
+	if _, ok := tlsConn.(*tls.Conn); !ok {
+		t.Errorf("TLS listener returned %T; want *tls.Conn", tlsConn)
+	}
+
+	plainClient, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plainClient.Close()
+	if _, err := plainClient.Write([]byte("GET / HTTP/1.0\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	plainConn, err := plainListener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plainConn.Close()
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(plainConn, buf); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != "GET / HTTP/1.0\r\n" {
+		t.Errorf("plaintext connection read %q; want the bytes sent by the client", buf)
+	}
+
+	silent.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := silent.Read(buf); err != io.EOF {
+		t.Errorf("silent client read returned %v; want EOF", err)
+	}
+}
+
+func TestSniffMaxConcurrent(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sniffConfig := &SniffConfig{Timeout: 100 * time.Millisecond, MaxConcurrent: 1}
+	tlsListener, plainListener := sniffConfig.NewListeners(inner)
+	defer tlsListener.Close()
+	defer plainListener.Close()
+
+	first, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if _, err := first.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first connection is sniffed but not accepted, so it occupies
+	// the only slot and the second connection isn't sniffed, which would
+	// close it because it sends nothing
+	second, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	buf := make([]byte, 1)
+	second.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	if _, err := second.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("second connection read returned %v while the limit was reached; want a timeout", err)
+	}
+
+	conn, err := plainListener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := second.Read(buf); err != io.EOF {
+		t.Errorf("second connection read returned %v after the limit was lifted; want EOF", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"errors"
+	"net"
+)
+
+// Create a listening UNIX domain socket with the given name in the Linux
+// abstract socket namespace.  Abstract sockets have no presence in the
+// filesystem, so they have no permissions (Mode is ignored), they don't need
+// to be removed, and they can't be replaced by another process.  The socket is
+// automatically released when the net.Listener is closed.
+func (lc *ListenConfig) ListenAbstract(name string) (net.Listener, error) {
+	network, err := lc.network()
+	if err != nil {
+		return nil, err
+	}
+	if lc.Lock {
+		return nil, errors.New("Lock is not supported for abstract UNIX domain sockets")
+	}
+	if name == "" {
+		return nil, errors.New("abstract UNIX domain socket name is empty")
+	}
+	listener, err := net.ListenUnix(network, &net.UnixAddr{Net: network, Name: "@" + name})
+	if err != nil {
+		return nil, err
+	}
+	return &unixListener{listener: listener, config: *lc}, nil
+}
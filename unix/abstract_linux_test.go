@@ -0,0 +1,97 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+)
+
+func testAbstractName(t *testing.T) string {
+	return fmt.Sprintf("go-listener-%s-%d", t.Name(), os.Getpid())
+}
+
+func TestListenAbstract(t *testing.T) {
+	name := testAbstractName(t)
+	listener, err := ListenAbstract(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listener.Addr().String(), "@"+name; got != want {
+		t.Errorf("Addr returned %q, want %q", got, want)
+	}
+
+	client, err := net.Dial("unix", "@"+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if UnwrapConn(conn) == nil {
+		t.Errorf("Accept returned %T, not a *Conn", conn)
+	}
+	if _, err := client.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := conn.Read(buf); err != nil || string(buf) != "hello" {
+		t.Errorf("Read returned %q, %v", buf, err)
+	}
+	conn.Close()
+
+	// Closing the listener releases the name
+	listener.Close()
+	listener, err = ListenAbstract(name)
+	if err != nil {
+		t.Fatalf("ListenAbstract failed after closing the previous listener: %s", err)
+	}
+	listener.Close()
+}
+
+func TestListenAbstractInUse(t *testing.T) {
+	name := testAbstractName(t)
+	listener, err := ListenAbstract(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if second, err := ListenAbstract(name); err == nil {
+		second.Close()
+		t.Error("ListenAbstract succeeded for a name which is in use")
+	}
+}
+
+func TestListenAbstractEmptyName(t *testing.T) {
+	if listener, err := ListenAbstract(""); err == nil {
+		listener.Close()
+		t.Error("ListenAbstract succeeded with an empty name")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"net"
+	"os"
+)
+
+// Credentials identifies the process on the other end of a UNIX domain
+// socket connection.  The credentials are captured by the kernel when
+// the connection is established.
+type Credentials struct {
+	PID int
+	UID int
+	GID int
+}
+
+// Conn is a connection accepted by a listener created by this package.
+type Conn struct {
+	*net.UnixConn
+}
+
+// Return the [*Conn] underlying conn, or nil if there isn't one.  If conn
+// is not itself a *Conn, UnwrapConn follows the chain of NetConn methods,
+// like the ones provided by [crypto/tls.Conn] and by connections accepted
+// from a PROXY protocol listener.  If the chain ends in a [*net.UnixConn]
+// which wasn't accepted from a listener created by this package, it is
+// wrapped in a new *Conn.
+func UnwrapConn(conn net.Conn) *Conn {
+	for {
+		switch c := conn.(type) {
+		case *Conn:
+			return c
+		case *net.UnixConn:
+			return &Conn{UnixConn: c}
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil
+		}
+	}
+}
+
+// Return the credentials of the peer process.  Only supported on Linux.
+func (conn *Conn) PeerCredentials() (*Credentials, error) {
+	return peerCredentials(conn.UnixConn)
+}
+
+// Return the security context (e.g. SELinux label) of the peer process,
+// as reported by SO_PEERSEC.  Only supported on Linux, and only when a
+// Linux Security Module which provides security contexts is active.
+func (conn *Conn) PeerSecurityContext() (string, error) {
+	return peerSecurityContext(conn.UnixConn)
+}
+
+// Return a pidfd referring to the peer process, as reported by SO_PEERPIDFD.
+// Unlike the PID in [Credentials], a pidfd cannot be recycled to refer to a
+// different process.  Only supported on Linux 6.5 and higher.  The caller
+// is responsible for closing the returned file.
+func (conn *Conn) PeerPidfd() (*os.File, error) {
+	return peerPidfd(conn.UnixConn)
+}
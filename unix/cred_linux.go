@@ -0,0 +1,78 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+const soPeerPidfd = 77 // SO_PEERPIDFD, not defined by package syscall
+
+func controlConn(conn *net.UnixConn, f func(fd int) error) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := rawConn.Control(func(fd uintptr) { ferr = f(int(fd)) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+func peerCredentials(conn *net.UnixConn) (*Credentials, error) {
+	var ucred *syscall.Ucred
+	err := controlConn(conn, func(fd int) (err error) {
+		ucred, err = syscall.GetsockoptUcred(fd, syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+		return
+	})
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt SO_PEERCRED", err)
+	}
+	return &Credentials{
+		PID: int(ucred.Pid),
+		UID: int(ucred.Uid),
+		GID: int(ucred.Gid),
+	}, nil
+}
+
+func peerPidfd(conn *net.UnixConn) (*os.File, error) {
+	var pidfd int
+	err := controlConn(conn, func(fd int) (err error) {
+		pidfd, err = syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, soPeerPidfd)
+		return
+	})
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt SO_PEERPIDFD", err)
+	}
+	return os.NewFile(uintptr(pidfd), "pidfd"), nil
+}
+
+func checkPeerCredentialsSupport() error {
+	return nil
+}
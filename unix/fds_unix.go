@@ -0,0 +1,118 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+//go:build unix
+
+package unix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Send data to the peer along with the given files, using SCM_RIGHTS
+// ancillary data.  At least one byte of data must be sent.  The files
+// remain open in this process and may be closed once SendFDs returns.
+func (conn *Conn) SendFDs(data []byte, files ...*os.File) (int, error) {
+	if len(data) == 0 {
+		return 0, errors.New("at least one byte of data must be sent along with file descriptors")
+	}
+	return conn.sendFDs(data, files, make([]int, 0, len(files)))
+}
+
+// Send data along with fds and the file descriptors of files.  Each file's
+// descriptor is obtained with [syscall.RawConn.Control], which prevents it
+// from being closed (and possibly reused) until the message has been sent,
+// and, unlike [os.File.Fd], leaves the file in non-blocking mode.
+func (conn *Conn) sendFDs(data []byte, files []*os.File, fds []int) (int, error) {
+	if len(files) == 0 {
+		n, _, err := conn.WriteMsgUnix(data, syscall.UnixRights(fds...), nil)
+		return n, err
+	}
+	rawConn, err := files[0].SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	controlErr := rawConn.Control(func(fd uintptr) {
+		n, err = conn.sendFDs(data, files[1:], append(fds, int(fd)))
+	})
+	if controlErr != nil {
+		return 0, controlErr
+	}
+	return n, err
+}
+
+// Receive data from the peer into buf, along with up to maxFiles files
+// sent using SCM_RIGHTS ancillary data.  The received file descriptors are
+// close-on-exec.  If the peer sent more than maxFiles files, RecvFDs
+// closes all of the received file descriptors and returns an error.
+func (conn *Conn) RecvFDs(buf []byte, maxFiles int) (int, []*os.File, error) {
+	oob := make([]byte, syscall.CmsgSpace(maxFiles*4))
+
+	// On Linux and most BSDs, ReadMsgUnix passes MSG_CMSG_CLOEXEC to recvmsg
+	// so received file descriptors are atomically marked close-on-exec;
+	// elsewhere, it marks them close-on-exec immediately after receipt.
+	n, oobn, flags, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return n, nil, err
+	}
+
+	var fds []int
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return n, nil, fmt.Errorf("error parsing ancillary data: %w", err)
+	}
+	for i := range messages {
+		if messages[i].Header.Level != syscall.SOL_SOCKET || messages[i].Header.Type != syscall.SCM_RIGHTS {
+			continue
+		}
+		rights, err := syscall.ParseUnixRights(&messages[i])
+		if err != nil {
+			closeFDs(fds)
+			return n, nil, fmt.Errorf("error parsing SCM_RIGHTS message: %w", err)
+		}
+		fds = append(fds, rights...)
+	}
+
+	if flags&syscall.MSG_CTRUNC != 0 || len(fds) > maxFiles {
+		closeFDs(fds)
+		return n, nil, fmt.Errorf("peer sent more than %d file descriptors", maxFiles)
+	}
+
+	files := make([]*os.File, len(fds))
+	for i, fd := range fds {
+		files[i] = os.NewFile(uintptr(fd), fmt.Sprintf("fd %d from peer", fd))
+	}
+	return n, files, nil
+}
+
+func closeFDs(fds []int) {
+	for _, fd := range fds {
+		syscall.Close(fd)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+//go:build unix
+
+package unix
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func socketpair(t *testing.T) (*Conn, *Conn) {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newConn := func(fd int) *Conn {
+		file := os.NewFile(uintptr(fd), "socketpair")
+		defer file.Close()
+		conn, err := net.FileConn(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return &Conn{conn.(*net.UnixConn)}
+	}
+	return newConn(fds[0]), newConn(fds[1])
+}
+
+func TestSendRecvFDs(t *testing.T) {
+	sender, receiver := socketpair(t)
+
+	pipeReader, pipeWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipeReader.Close()
+	defer pipeWriter.Close()
+	file, err := os.CreateTemp(t.TempDir(), "fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("file contents"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := sender.SendFDs([]byte("x"), pipeReader, file); err != nil {
+		t.Fatalf("SendFDs failed: %s", err)
+	} else if n != 1 {
+		t.Fatalf("SendFDs sent %d bytes; want 1", n)
+	}
+	// The files may be closed as soon as SendFDs returns
+	file.Close()
+
+	buf := make([]byte, 16)
+	n, files, err := receiver.RecvFDs(buf, 2)
+	if err != nil {
+		t.Fatalf("RecvFDs failed: %s", err)
+	}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	if string(buf[:n]) != "x" {
+		t.Errorf("RecvFDs received %q; want \"x\"", buf[:n])
+	}
+	if len(files) != 2 {
+		t.Fatalf("RecvFDs received %d files; want 2", len(files))
+	}
+
+	if _, err := pipeWriter.WriteString("pipe contents"); err != nil {
+		t.Fatal(err)
+	}
+	pipeWriter.Close()
+	if data, err := io.ReadAll(files[0]); err != nil {
+		t.Errorf("reading received pipe: %s", err)
+	} else if string(data) != "pipe contents" {
+		t.Errorf("received pipe contains %q", data)
+	}
+	if data, err := io.ReadAll(io.NewSectionReader(files[1], 0, 100)); err != nil {
+		t.Errorf("reading received file: %s", err)
+	} else if string(data) != "file contents" {
+		t.Errorf("received file contains %q", data)
+	}
+}
+
+func TestRecvTooManyFDs(t *testing.T) {
+	sender, receiver := socketpair(t)
+
+	if _, err := sender.SendFDs([]byte("x"), os.Stdin, os.Stdout); err != nil {
+		t.Fatalf("SendFDs failed: %s", err)
+	}
+	buf := make([]byte, 16)
+	if _, files, err := receiver.RecvFDs(buf, 1); err == nil {
+		for _, file := range files {
+			file.Close()
+		}
+		t.Fatal("RecvFDs succeeded with more than maxFiles files")
+	}
+}
+
+func TestSendFDsWithoutData(t *testing.T) {
+	sender, _ := socketpair(t)
+	if _, err := sender.SendFDs(nil, os.Stdin); err == nil {
+		t.Fatal("SendFDs succeeded without data")
+	}
+}
@@ -27,39 +27,192 @@
 package unix // import "src.agwa.name/go-listener/unix"
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type watchedListener struct {
+// ListenConfig contains options for creating a UNIX domain socket listener.
+// The zero value is a valid configuration.
+type ListenConfig struct {
+	// The type of socket: "unix" (SOCK_STREAM) or "unixpacket"
+	// (SOCK_SEQPACKET).  If empty, "unix" is used.
+	Network string
+
+	// Filesystem permissions of the socket file
+	Mode os.FileMode
+
+	// If either AllowUIDs or AllowGIDs is non-empty, then connections are
+	// only accepted from peers whose user ID is listed in AllowUIDs or whose
+	// group ID is listed in AllowGIDs.  Connections from other peers are closed
+	// without being returned from Accept.  Only supported on Linux.
+	AllowUIDs []int
+	AllowGIDs []int
+
+	// If true, take an exclusive lock on a file named PATH.lock before
+	// creating the socket, and fail if another process holds the lock.  While
+	// holding the lock, only replace an existing file at PATH if it is a
+	// socket that is no longer accepting connections.  The lock is released
+	// when the listener is closed or the socket file is removed or replaced.
+	// Lock is not supported by ListenAbstract or NewListener.
+	Lock bool
+
+	// If non-nil, OnReplaced is called with the path of the socket file
+	// when the file is removed or replaced, after the listener has been
+	// closed.  Applications can use this to re-create the socket or exit.
+	OnReplaced func(path string)
+}
+
+func (lc *ListenConfig) network() (string, error) {
+	switch lc.Network {
+	case "":
+		return "unix", nil
+	case "unix", "unixpacket":
+		return lc.Network, nil
+	default:
+		return "", fmt.Errorf("unsupported UNIX domain socket network %q", lc.Network)
+	}
+}
+
+func (lc *ListenConfig) restrictsPeers() bool {
+	return len(lc.AllowUIDs) > 0 || len(lc.AllowGIDs) > 0
+}
+
+func (lc *ListenConfig) isAllowed(cred *Credentials) bool {
+	return slices.Contains(lc.AllowUIDs, cred.UID) || slices.Contains(lc.AllowGIDs, cred.GID)
+}
+
+type unixListener struct {
 	listener *net.UnixListener
-	closed   chan struct{}
+	config   ListenConfig
 }
 
-func newWatchedListener(listener *net.UnixListener) *watchedListener {
-	return &watchedListener{
-		listener: listener,
-		closed:   make(chan struct{}),
+func (ul *unixListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := ul.listener.AcceptUnix()
+		if err != nil {
+			return nil, err
+		}
+		if ul.config.restrictsPeers() {
+			if cred, err := peerCredentials(conn); err != nil || !ul.config.isAllowed(cred) {
+				conn.Close()
+				continue
+			}
+		}
+		return &Conn{UnixConn: conn}, nil
+	}
+}
+
+func (ul *unixListener) Close() error {
+	return ul.listener.Close()
+}
+
+func (ul *unixListener) Addr() net.Addr {
+	return ul.listener.Addr()
+}
+
+// Wrap an existing listening UNIX domain socket, such as one inherited from a
+// parent process, so that accepted connections are returned as [*Conn] and
+// are subject to AllowUIDs and AllowGIDs.  Mode is ignored.
+func (lc *ListenConfig) NewListener(listener *net.UnixListener) (net.Listener, error) {
+	if lc.Lock {
+		return nil, errors.New("Lock is not supported for existing UNIX domain socket listeners")
 	}
+	if lc.restrictsPeers() {
+		if err := checkPeerCredentialsSupport(); err != nil {
+			return nil, err
+		}
+	}
+	return &unixListener{listener: listener, config: *lc}, nil
+}
+
+// ErrSocketReplaced is returned by Accept when the UNIX domain socket
+// file has been removed or replaced by another file.
+var ErrSocketReplaced = errors.New("UNIX domain socket file was removed or replaced")
+
+type watchedListener struct {
+	unixListener
+	path       string
+	info       os.FileInfo
+	onReplaced func(string)
+	watcher    io.Closer
+	lockFile   *os.File
+	lockOnce   sync.Once
+	closed     chan struct{}
+	closeOnce  sync.Once
+	replaced   atomic.Bool
 }
 
 func (wl *watchedListener) Accept() (net.Conn, error) {
-	return wl.listener.Accept()
+	conn, err := wl.unixListener.Accept()
+	if err != nil && wl.replaced.Load() {
+		return nil, ErrSocketReplaced
+	}
+	return conn, err
 }
 
 func (wl *watchedListener) Close() error {
-	close(wl.closed)
-	return wl.listener.Close()
+	wl.closeOnce.Do(func() {
+		close(wl.closed)
+		if wl.watcher != nil {
+			wl.watcher.Close()
+		}
+	})
+	err := wl.listener.Close()
+	wl.releaseLock()
+	return err
+}
+
+func (wl *watchedListener) releaseLock() {
+	wl.lockOnce.Do(func() {
+		if wl.lockFile != nil {
+			wl.lockFile.Close()
+		}
+	})
 }
 
 func (wl *watchedListener) Addr() net.Addr {
-	return wl.listener.Addr()
+	return &net.UnixAddr{Net: wl.listener.Addr().Network(), Name: wl.path}
 }
 
-func (wl *watchedListener) watch(path string, info os.FileInfo) {
+func (wl *watchedListener) isClosed() bool {
+	select {
+	case <-wl.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Check if the socket file is still in place.  If it isn't, close the
+// underlying listener, release the lock so that a replacement listener can
+// acquire it, invoke the OnReplaced callback, and return true.
+func (wl *watchedListener) check() bool {
+	latestInfo, err := os.Lstat(wl.path)
+	if err == nil && os.SameFile(wl.info, latestInfo) {
+		return false
+	}
+	if wl.isClosed() {
+		return true
+	}
+	wl.replaced.Store(true)
+	wl.listener.Close()
+	wl.releaseLock()
+	if wl.onReplaced != nil {
+		wl.onReplaced(wl.path)
+	}
+	return true
+}
+
+func (wl *watchedListener) poll() {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 	for {
@@ -67,20 +220,81 @@ func (wl *watchedListener) watch(path string, info os.FileInfo) {
 		case <-wl.closed:
 			return
 		case <-ticker.C:
-			latestInfo, err := os.Lstat(path)
-			if !(err == nil && os.SameFile(info, latestInfo)) {
-				wl.listener.Close()
+			if wl.check() {
 				return
 			}
 		}
 	}
 }
 
+// Return an error unless path doesn't exist or is a socket which is no
+// longer accepting connections.
+func checkStaleSocket(network string, path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s already exists and is not a socket", path)
+	}
+	return withShortPath(path, func(shortPath string) error {
+		if conn, err := net.Dial(network, shortPath); err == nil {
+			conn.Close()
+			return fmt.Errorf("%s is in use by another process", path)
+		}
+		return nil
+	})
+}
+
 // Create a listening UNIX domain socket with the given path and filesystem
 // permissions.  If a file already exists at the path, it is replaced.  If
-// the UNIX domain socket file is removed or changed, then within 5 seconds
-// the net.Listener will be closed, and Accept will return an error.
+// the UNIX domain socket file is removed or changed, then the net.Listener
+// will be closed, and Accept will return [ErrSocketReplaced].  On Linux,
+// this happens immediately; on other operating systems, within 5 seconds.
 func Listen(path string, mode os.FileMode) (net.Listener, error) {
+	return (&ListenConfig{Mode: mode}).Listen(path)
+}
+
+// Create a listening UNIX domain socket with the given name in the Linux
+// abstract socket namespace, using the default [ListenConfig].
+func ListenAbstract(name string) (net.Listener, error) {
+	return new(ListenConfig).ListenAbstract(name)
+}
+
+// Create a listening UNIX domain socket with the given path, as described
+// in the documentation for [Listen].  Accepted connections are returned as [*Conn].
+// When Network is "unixpacket", each Read from a connection returns exactly one
+// message, and each Write sends exactly one message.  On Linux, path may be
+// longer than the operating system's limit for socket addresses (107 bytes).
+func (lc *ListenConfig) Listen(path string) (net.Listener, error) {
+	network, err := lc.network()
+	if err != nil {
+		return nil, err
+	}
+	if lc.restrictsPeers() {
+		if err := checkPeerCredentialsSupport(); err != nil {
+			return nil, err
+		}
+	}
+
+	var lockFile *os.File
+	if lc.Lock {
+		lockFile, err = acquireLock(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if lockFile != nil {
+				lockFile.Close()
+			}
+		}()
+		if err := checkStaleSocket(network, path); err != nil {
+			return nil, err
+		}
+	}
+
 	tempDir, err := os.MkdirTemp(filepath.Dir(path), ".tmp")
 	if err != nil {
 		return nil, fmt.Errorf("error creating temporary directory to hold Unix socket: %w", err)
@@ -88,8 +302,11 @@ func Listen(path string, mode os.FileMode) (net.Listener, error) {
 	defer os.Remove(tempDir)
 
 	tempPath := filepath.Join(tempDir, "socket")
-	tempListener, err := net.ListenUnix("unix", &net.UnixAddr{Net: "unix", Name: tempPath})
-	if err != nil {
+	var tempListener *net.UnixListener
+	if err := withShortPath(tempPath, func(shortPath string) (err error) {
+		tempListener, err = net.ListenUnix(network, &net.UnixAddr{Net: network, Name: shortPath})
+		return
+	}); err != nil {
 		return nil, err
 	}
 	tempListener.SetUnlinkOnClose(false)
@@ -100,7 +317,7 @@ func Listen(path string, mode os.FileMode) (net.Listener, error) {
 		}
 	}()
 
-	if err := os.Chmod(tempPath, mode); err != nil {
+	if err := os.Chmod(tempPath, lc.Mode); err != nil {
 		return nil, err
 	}
 
@@ -113,9 +330,17 @@ func Listen(path string, mode os.FileMode) (net.Listener, error) {
 		return nil, err
 	}
 
-	listener := newWatchedListener(tempListener)
+	listener := &watchedListener{
+		unixListener: unixListener{listener: tempListener, config: *lc},
+		path:         path,
+		info:         fileInfo,
+		onReplaced:   lc.OnReplaced,
+		lockFile:     lockFile,
+		closed:       make(chan struct{}),
+	}
 	tempListener = nil
+	lockFile = nil
 
-	go listener.watch(path, fileInfo)
+	listener.startWatching()
 	return listener, nil
 }
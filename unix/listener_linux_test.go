@@ -0,0 +1,253 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeerCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	listener, err := Listen(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	cred, err := UnwrapConn(conn).PeerCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Credentials{PID: os.Getpid(), UID: os.Getuid(), GID: os.Getgid()}); *cred != want {
+		t.Errorf("PeerCredentials returned %+v, want %+v", *cred, want)
+	}
+}
+
+func TestAllowUIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	listener, err := (&ListenConfig{Mode: 0600, AllowUIDs: []int{os.Getuid()}}).Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestAllowUIDsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	listener, err := (&ListenConfig{Mode: 0600, AllowUIDs: []int{os.Getuid() + 1}, AllowGIDs: []int{os.Getgid() + 1}}).Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if conn != nil {
+			conn.Close()
+			err = errors.New("Accept returned a connection from a peer that isn't allowed")
+		}
+		accepted <- err
+	}()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read from rejected connection returned %v, want EOF", err)
+	}
+
+	listener.Close()
+	if err := <-accepted; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept returned %v, want net.ErrClosed", err)
+	}
+}
+
+func testReplaced(t *testing.T, replace func(path string) error) {
+	path := filepath.Join(t.TempDir(), "socket")
+	replaced := make(chan string, 1)
+	listener, err := (&ListenConfig{Mode: 0600, OnReplaced: func(path string) { replaced <- path }}).Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if conn != nil {
+			conn.Close()
+		}
+		accepted <- err
+	}()
+
+	if err := replace(path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-replaced:
+		if got != path {
+			t.Errorf("OnReplaced called with %q, want %q", got, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnReplaced was not called")
+	}
+	if err := <-accepted; !errors.Is(err, ErrSocketReplaced) {
+		t.Errorf("Accept returned %v, want ErrSocketReplaced", err)
+	}
+}
+
+func TestSocketRemoved(t *testing.T) {
+	testReplaced(t, os.Remove)
+}
+
+func TestSocketReplaced(t *testing.T) {
+	testReplaced(t, func(path string) error {
+		newPath := path + ".new"
+		if err := os.WriteFile(newPath, nil, 0600); err != nil {
+			return err
+		}
+		return os.Rename(newPath, path)
+	})
+}
+
+func TestLockUnsupported(t *testing.T) {
+	config := &ListenConfig{Lock: true}
+	if listener, err := config.ListenAbstract("go-listener-test"); err == nil {
+		listener.Close()
+		t.Error("ListenAbstract succeeded with Lock set")
+	}
+
+	inner, err := net.ListenUnix("unix", &net.UnixAddr{Net: "unix", Name: filepath.Join(t.TempDir(), "socket")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+	if _, err := config.NewListener(inner); err == nil {
+		t.Error("NewListener succeeded with Lock set")
+	}
+}
+
+func TestLockReleasedOnReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	replaced := make(chan struct{})
+	config := &ListenConfig{Mode: 0600, Lock: true, OnReplaced: func(string) { close(replaced) }}
+	listener, err := config.Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	if second, err := (&ListenConfig{Mode: 0600, Lock: true}).Listen(path); err == nil {
+		second.Close()
+		t.Fatal("second Listen succeeded while the lock was held")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-replaced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnReplaced was not called")
+	}
+
+	second, err := (&ListenConfig{Mode: 0600, Lock: true}).Listen(path)
+	if err != nil {
+		t.Fatalf("Listen failed after the socket was removed: %s", err)
+	}
+	second.Close()
+}
+
+func TestUnixPacketMessageBoundaries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	listener, err := (&ListenConfig{Network: "unixpacket", Mode: 0600}).Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unixpacket", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, message := range []string{"first message", "second"} {
+		if _, err := client.Write([]byte(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A short buffer truncates the first message rather than leaving the
+	// remainder to be returned by the next Read
+	buf := make([]byte, 5)
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "first" {
+		t.Errorf("first Read returned %q, %v; want %q", buf[:n], err, "first")
+	}
+	buf = make([]byte, 64)
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "second" {
+		t.Errorf("second Read returned %q, %v; want %q", buf[:n], err, "second")
+	}
+}
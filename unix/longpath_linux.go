@@ -0,0 +1,64 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const (
+	maxPathLen = 107      // size of sun_path, less the NUL terminator
+	oPath      = 0x200000 // O_PATH, not defined by package syscall
+)
+
+// Call f with a path that refers to the same location as path but fits
+// in sun_path.  If path is too long, the short path goes through
+// /proc/self/fd/N, where N is an O_PATH file descriptor for the directory
+// containing path.
+func withShortPath(path string, f func(string) error) error {
+	if len(path) <= maxPathLen {
+		return f(path)
+	}
+
+	dir, name := filepath.Split(path)
+	dirfd, err := syscall.Open(dir, oPath|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("UNIX domain socket path %q is longer than %d bytes, and opening its directory to shorten it failed: %w", path, maxPathLen, os.NewSyscallError("open", err))
+	}
+	defer syscall.Close(dirfd)
+
+	shortPath := fmt.Sprintf("/proc/self/fd/%d/%s", dirfd, name)
+	if len(shortPath) > maxPathLen {
+		return fmt.Errorf("UNIX domain socket file name %q is too long (the maximum is about %d bytes)", name, maxPathLen-len(shortPath)+len(name))
+	}
+	if _, err := os.Stat(fmt.Sprintf("/proc/self/fd/%d", dirfd)); err != nil {
+		return fmt.Errorf("UNIX domain socket path %q is longer than %d bytes, and /proc is not available to shorten it: %w", path, maxPathLen, err)
+	}
+	return f(shortPath)
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLongPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, length := range []int{107, 108, 200} {
+		subdir := filepath.Join(dir, strings.Repeat("d", length-len(dir)-len("//socket")))
+		if err := os.MkdirAll(subdir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(subdir, "socket")
+		if len(path) != length {
+			t.Fatalf("test path has length %d, want %d", len(path), length)
+		}
+
+		var listener *net.UnixListener
+		if err := withShortPath(path, func(shortPath string) (err error) {
+			listener, err = net.ListenUnix("unix", &net.UnixAddr{Net: "unix", Name: shortPath})
+			return
+		}); err != nil {
+			t.Errorf("binding %d byte path failed: %s", length, err)
+			continue
+		}
+
+		if err := withShortPath(path, func(shortPath string) error {
+			conn, err := net.Dial("unix", shortPath)
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		}); err != nil {
+			t.Errorf("dialing %d byte path failed: %s", length, err)
+		}
+		listener.Close()
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+//go:build linux && !386 && !s390x
+
+package unix
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func peerSecurityContext(conn *net.UnixConn) (string, error) {
+	buf := make([]byte, 256)
+	err := controlConn(conn, func(fd int) error {
+		for {
+			length := uint32(len(buf))
+			_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), syscall.SOL_SOCKET, syscall.SO_PEERSEC, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&length)), 0)
+			if errno == syscall.ERANGE && int(length) > len(buf) {
+				buf = make([]byte, length)
+				continue
+			} else if errno != 0 {
+				return errno
+			}
+			buf = buf[:length]
+			return nil
+		}
+	})
+	if err != nil {
+		return "", os.NewSyscallError("getsockopt SO_PEERSEC", err)
+	}
+	return string(bytes.TrimRight(buf, "\x00")), nil
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Andrew Ayer
+//
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// Except as contained in this notice, the name(s) of the above copyright
+// holders shall not be used in advertising or otherwise to promote the
+// sale, use or other dealings in this Software without prior written
+// authorization.
+
+package unix
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// Watch the directory containing the socket with inotify, so that removal
+// or replacement is detected immediately.  Fall back to polling if inotify
+// is unavailable.
+func (wl *watchedListener) startWatching() {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		go wl.poll()
+		return
+	}
+	// Since fd is non-blocking, os.NewFile registers it with the runtime
+	// poller, which allows Close to interrupt a blocked Read.
+	watcher := os.NewFile(uintptr(fd), "inotify")
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(wl.path), inotifyMask); err != nil {
+		watcher.Close()
+		go wl.poll()
+		return
+	}
+	wl.watcher = watcher
+	go wl.watchInotify(watcher)
+}
+
+func (wl *watchedListener) watchInotify(watcher *os.File) {
+	defer watcher.Close()
+
+	// The socket may have been replaced before the watch was added
+	if wl.check() {
+		return
+	}
+
+	name := filepath.Base(wl.path)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := watcher.Read(buf)
+		if err != nil {
+			if !wl.isClosed() {
+				wl.poll()
+			}
+			return
+		}
+		if eventsConcern(buf[:n], name) && wl.check() {
+			return
+		}
+	}
+}
+
+// Report whether any of the inotify events in buf concern the file with
+// the given name, or the watched directory itself.
+func eventsConcern(buf []byte, name string) bool {
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		end := syscall.SizeofInotifyEvent + int(event.Len)
+		if end > len(buf) {
+			return true
+		}
+		if event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF|syscall.IN_IGNORED|syscall.IN_Q_OVERFLOW) != 0 {
+			return true
+		}
+		if string(bytes.TrimRight(buf[syscall.SizeofInotifyEvent:end], "\x00")) == name {
+			return true
+		}
+		buf = buf[end:]
+	}
+	return false
+}